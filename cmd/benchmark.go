@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/otaviohenrique/zamorak/pkg/decoder"
+	"github.com/otaviohenrique/zamorak/pkg/emulator"
+	"github.com/otaviohenrique/zamorak/pkg/headless"
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+)
+
+// runBenchmark runs frames frames without a window or any throttling and
+// prints how fast the emulator went, compared to a real 60 Hz machine.
+func runBenchmark(log *slog.Logger, quirks interpreter.Quirks, path string, programData []byte, ipf int, frames int) error {
+	emu, err := emulator.New(log, quirks, path, programData, headless.NewRuntime())
+	if err != nil {
+		return err
+	}
+
+	emu.SetIPF(ipf)
+
+	var instructions int
+	emu.AddHook(func(c *interpreter.Chip8, pc uint16, instr decoder.Instruction) {
+		instructions++
+	})
+
+	start := time.Now()
+
+	ran := 0
+	for ; ran < frames && emu.Stopped() == nil; ran++ {
+		emu.Update()
+	}
+
+	elapsed := time.Since(start)
+	seconds := elapsed.Seconds()
+
+	fmt.Printf("frames:       %d\n", ran)
+	fmt.Printf("instructions: %d\n", instructions)
+	fmt.Printf("elapsed:      %s\n", elapsed.Round(time.Microsecond))
+
+	if seconds > 0 {
+		fmt.Printf("frames/s:     %.0f (%.1fx real time)\n", float64(ran)/seconds, float64(ran)/seconds/60)
+		fmt.Printf("instr/s:      %.0f\n", float64(instructions)/seconds)
+	}
+
+	if err := emu.Stopped(); err != nil {
+		fmt.Printf("stopped:      %v\n", err)
+	}
+
+	return nil
+}
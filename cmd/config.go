@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/otaviohenrique/zamorak/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show and change the config file",
+	Long: `Settings are layered, later layers winning: flag defaults, the config
+file, the section of the file for the ROM being run (by file name or SHA-1),
+ZAMORAK_* environment variables and finally command line flags. ROM sections
+hold the profile, quirks and keymap of known ROMs, no compatibility database
+is bundled. Ex:
+
+zamorak config show /path/to/rom
+zamorak config set audio.volume 0.5
+zamorak config set --rom pong.ch8 keymap azerty`,
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the path of the config file",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := configPath(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not find the config file:", err)
+
+			os.Exit(1)
+		}
+
+		fmt.Println(path)
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show [rom]",
+	Short: "Print every setting, where it comes from and, with a ROM, its overrides",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := loadConfig(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not read the config file:", err)
+
+			os.Exit(1)
+		}
+
+		var romPath string
+		var programData []byte
+
+		if len(args) == 1 {
+			romPath = args[0]
+
+			programData, err = os.ReadFile(romPath)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Could not read ROM:", err)
+
+				os.Exit(1)
+			}
+
+			sections := f.Sections(romPath, programData)
+			if len(sections) == 0 {
+				sections = []string{"none"}
+			}
+
+			fmt.Printf("ROM sections: %s\n\n", strings.Join(sections, ", "))
+		}
+
+		settings := f.Resolve(romPath, programData, os.Environ())
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+
+		for _, k := range config.KEYS {
+			s, ok := settings[k.Name]
+			if !ok {
+				s = config.Setting{Key: k, Source: config.SourceDefault}
+
+				if flag := runCmd.Flags().Lookup(k.Flag); flag != nil {
+					s.Value = flag.DefValue
+				}
+			}
+
+			source := string(s.Source)
+			if s.Origin != "" {
+				source += " (" + s.Origin + ")"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\n", k.Name, s.Value, source)
+		}
+
+		w.Flush()
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set key value",
+	Short: "Change a setting, an empty value removes it",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		rom, _ := cmd.Flags().GetString("rom")
+
+		f, err := loadConfig(cmd)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not read the config file:", err)
+
+			os.Exit(1)
+		}
+
+		err = checkSetting(args[0], args[1])
+		if err == nil {
+			err = f.Set(rom, args[0], args[1])
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid setting:", err)
+			fmt.Fprintln(os.Stderr, "\nSettings:")
+
+			for _, k := range config.KEYS {
+				fmt.Fprintf(os.Stderr, "  %-18s %s\n", k.Name, k.Usage)
+			}
+
+			os.Exit(1)
+		}
+
+		if err := f.Save(); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not write the config file:", err)
+
+			os.Exit(1)
+		}
+	},
+}
+
+// checkSetting makes sure a setting sets a flag of run and that its value
+// parses as the type of that flag.
+func checkSetting(name string, value string) error {
+	k, ok := config.Lookup(name)
+	if !ok {
+		return fmt.Errorf("unknown setting %q", name)
+	}
+
+	flag := runCmd.Flags().Lookup(k.Flag)
+	if flag == nil {
+		return fmt.Errorf("setting %q has no --%s flag", name, k.Flag)
+	}
+
+	return config.ValidateValue(k, flag.Value.Type(), value)
+}
+
+// hasFlag reports whether cmd or one of its subcommands has a flag.
+func hasFlag(cmd *cobra.Command, name string) bool {
+	if cmd.Flags().Lookup(name) != nil {
+		return true
+	}
+
+	for _, sub := range cmd.Commands() {
+		if hasFlag(sub, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// configPath returns the file named by --config, or the default one.
+func configPath(cmd *cobra.Command) (string, error) {
+	if path, _ := cmd.Flags().GetString("config"); path != "" {
+		return path, nil
+	}
+
+	return config.Path()
+}
+
+func loadConfig(cmd *cobra.Command) (*config.File, error) {
+	path, err := configPath(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.Load(path)
+}
+
+// applyConfig sets every flag of cmd that was not given on the command line
+// to the value resolved from the config file, the sections of the ROM and the
+// environment.
+func applyConfig(cmd *cobra.Command, romPath string, programData []byte) error {
+	f, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range f.Resolve(romPath, programData, os.Environ()) {
+		// settings of other commands are skipped, settings of no command are
+		// a mistake in the config file
+		if !hasFlag(cmd.Root(), s.Key.Flag) {
+			return fmt.Errorf("%s from %s has no --%s flag", s.Key.Name, s.Origin, s.Key.Flag)
+		}
+
+		flag := cmd.Flags().Lookup(s.Key.Flag)
+		if flag == nil || flag.Changed {
+			continue
+		}
+
+		if err := flag.Value.Set(s.Value); err != nil {
+			return fmt.Errorf("%s from %s: %w", s.Key.Name, s.Origin, err)
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configPathCmd, configShowCmd, configSetCmd)
+
+	configSetCmd.Flags().String("rom", "", "Change the section of this ROM file name or SHA-1 instead of the global settings")
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/otaviohenrique/zamorak/pkg/capture"
+	"github.com/otaviohenrique/zamorak/pkg/display"
+	"github.com/otaviohenrique/zamorak/pkg/emulator"
+	"github.com/otaviohenrique/zamorak/pkg/headless"
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+	"github.com/otaviohenrique/zamorak/pkg/palette"
+)
+
+// headlessOptions says how long a headless run lasts and what it captures.
+type headlessOptions struct {
+	ipf     int
+	frames  int
+	seed    int64 // headless runs are always seeded, to be repeatable
+	palette palette.Palette
+	filter  *display.Filter // nil draws pixels as they are
+
+	screenshotFrame  int // 0 takes none
+	screenshotScale  int
+	screenshotStdout bool
+	captureDir       string
+
+	clip    *capture.Clip    // nil records no clip
+	streams *capture.Streams // nil writes no streams
+
+	recorderSize int
+	dumpDir      string
+	tracePath    string // empty writes no trace
+}
+
+// runHeadless runs a program without a window or throttling, for opts.frames
+// frames or until the screenshot frame when there is one, feeding every
+// frame to the clip and the streams. The flight recorder and the trace are
+// installed as in a windowed run.
+func runHeadless(log *slog.Logger, quirks interpreter.Quirks, path string, programData []byte, opts headlessOptions) error {
+	runtime := headless.NewRuntime()
+
+	emu, err := emulator.New(log, quirks, path, programData, runtime)
+	if err != nil {
+		return err
+	}
+
+	emu.Seed(opts.seed)
+	emu.SetIPF(opts.ipf)
+
+	closeTrace, err := instrumentEmulator(emu, programData, quirks, opts.recorderSize, opts.dumpDir, opts.tracePath, log)
+	if err != nil {
+		return fmt.Errorf("could not create trace: %w", err)
+	}
+	defer closeTrace()
+
+	var streamErr error
+
+	emu.OnFrame(func() {
+		fb := runtime.Framebuffer()
+
+		if opts.filter != nil {
+			opts.filter.Latch(fb)
+		}
+
+		if opts.clip != nil {
+			opts.clip.Frame(fb, opts.filter)
+		}
+
+		if opts.streams != nil && streamErr == nil {
+			streamErr = opts.streams.Frame(capture.Render(fb, opts.palette, opts.filter, 1), runtime.IsBeeping())
+		}
+	})
+
+	frames := opts.frames
+	if opts.screenshotFrame > 0 {
+		frames = opts.screenshotFrame
+	}
+
+	ran := 0
+	for ; ran < frames && emu.Stopped() == nil && streamErr == nil; ran++ {
+		emu.Update()
+	}
+
+	if err := emu.Stopped(); err != nil {
+		log.Info("Program stopped", "frame", ran, "reason", err)
+	}
+
+	if opts.streams != nil {
+		if err := opts.streams.Close(); err != nil && streamErr == nil {
+			streamErr = err
+		}
+
+		if streamErr != nil {
+			return fmt.Errorf("could not write streams: %w", streamErr)
+		}
+	}
+
+	if opts.clip != nil {
+		if err := opts.clip.Close(); err != nil {
+			return fmt.Errorf("could not save clip: %w", err)
+		}
+
+		log.Info("Clip saved", "path", opts.clip.Path(), "frames", opts.clip.Frames())
+	}
+
+	if opts.screenshotFrame == 0 {
+		return nil
+	}
+
+	img := capture.Render(runtime.Framebuffer(), opts.palette, opts.filter, opts.screenshotScale)
+
+	if opts.screenshotStdout {
+		return capture.WritePNG(os.Stdout, img)
+	}
+
+	saved, err := capture.SavePNG(img, opts.captureDir, path)
+	if err != nil {
+		return fmt.Errorf("could not save screenshot: %w", err)
+	}
+
+	log.Info("Screenshot saved", "path", saved, "frame", ran)
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"io"
+	"log/slog"
+
+	"github.com/otaviohenrique/zamorak/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+// addLogFlags registers the flags that configure logging.
+func addLogFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("log-level", "l", "INFO", "Log Level")
+	cmd.Flags().String("log-levels", "", "Levels per subsystem (cpu, display, input, audio, scheduler), ex: cpu=debug,audio=warn")
+	cmd.Flags().String("log-format", "text", "Log format: text or json")
+	cmd.Flags().String("log-file", "", "Write logs to this file instead of stdout, or stderr when stdout carries data or the game is drawn in the terminal")
+	cmd.Flags().Int64("log-max-size", 10, "Rotate the log file after this many megabytes, 0 never rotates")
+	cmd.Flags().Int("log-max-backups", 3, "Rotated log files to keep")
+}
+
+// newLogger builds the logger described by the log flags. Without a log file
+// logs go to output, or to stdout when it is nil. The closer must be closed
+// before exiting to flush the log file.
+func newLogger(cmd *cobra.Command, output io.Writer) (*slog.Logger, io.Closer, error) {
+	level, _ := cmd.Flags().GetString("log-level")
+	levelSpec, _ := cmd.Flags().GetString("log-levels")
+	format, _ := cmd.Flags().GetString("log-format")
+	file, _ := cmd.Flags().GetString("log-file")
+	maxSize, _ := cmd.Flags().GetInt64("log-max-size")
+	maxBackups, _ := cmd.Flags().GetInt("log-max-backups")
+
+	levels, err := logger.ParseLevels(levelSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return logger.New(logger.Config{
+		Level:      level,
+		Levels:     levels,
+		Format:     format,
+		File:       file,
+		Output:     output,
+		MaxSize:    maxSize << 20,
+		MaxBackups: maxBackups,
+		Source:     true,
+	})
+}
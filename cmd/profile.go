@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/otaviohenrique/zamorak/pkg/decoder"
+	"github.com/otaviohenrique/zamorak/pkg/detect"
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+	"github.com/spf13/cobra"
+)
+
+// addProfileFlags registers the flags that select the platform and quirks a
+// program is run with.
+func addProfileFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("profile", "p", "", "Platform the ROM targets: chip8, schip, xochip or megachip. chip8 when empty, after scanning the ROM and suggesting a profile if it looks like another platform")
+	cmd.Flags().StringP("quirks", "q", "", "Quirks to override on top of the profile, ex: shifting,memory=off")
+}
+
+// resolveQuirks returns the quirks selected by --profile and --quirks. When no
+// profile is given the program is scanned and a warning is logged if it looks
+// like it targets another platform.
+func resolveQuirks(cmd *cobra.Command, programData []byte, log *slog.Logger) (decoder.Platform, interpreter.Quirks, error) {
+	profile, _ := cmd.Flags().GetString("profile")
+	quirkSpec, _ := cmd.Flags().GetString("quirks")
+
+	platform := decoder.PlatformCHIP8
+
+	if profile == "" {
+		report := detect.Scan(programData)
+
+		if suggestion := report.Suggestion(); suggestion != "" {
+			log.Warn("ROM may not be a CHIP-8 program", "suggestion", suggestion)
+		}
+	} else {
+		p, err := decoder.ParsePlatform(profile)
+		if err != nil {
+			return platform, interpreter.Quirks{}, fmt.Errorf("invalid profile: %w", err)
+		}
+
+		platform = p
+	}
+
+	if platform != decoder.PlatformCHIP8 {
+		log.Warn("Only CHIP-8 instructions are implemented, the ROM may not run correctly", "profile", platform)
+	}
+
+	quirks, err := interpreter.ParseQuirks(interpreter.QuirksForProfile(platform), quirkSpec)
+	if err != nil {
+		return platform, quirks, fmt.Errorf("invalid quirks: %w", err)
+	}
+
+	return platform, quirks, nil
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/otaviohenrique/zamorak/pkg/headless"
+	"github.com/otaviohenrique/zamorak/pkg/quirks"
+	"github.com/spf13/cobra"
+)
+
+// quirksCmd represents the quirks command
+var quirksCmd = &cobra.Command{
+	Use:   "quirks",
+	Short: "Find the quirks a CHIP-8 program depends on",
+	Long: `Run a CHIP-8 program headlessly under every permutation of the quirks,
+with the same input, and report which quirks change what ends up on screen.
+Input is random unless keys are scripted with --press. Ex:
+
+zamorak quirks /path/to/rom
+zamorak quirks --frames 1200 --press 60:5,120:6 /path/to/rom`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		frames, _ := cmd.Flags().GetInt("frames")
+		seed, _ := cmd.Flags().GetInt64("seed")
+		press, _ := cmd.Flags().GetString("press")
+		hold, _ := cmd.Flags().GetInt("hold")
+
+		programData, err := os.ReadFile(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not read ROM:", err)
+
+			os.Exit(1)
+		}
+
+		if err := applyConfig(cmd, args[0], programData); err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid config:", err)
+
+			os.Exit(1)
+		}
+
+		log, logCloser, err := newLogger(cmd, nil)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid log flags:", err)
+
+			os.Exit(1)
+		}
+		defer logCloser.Close()
+
+		_, base, err := resolveQuirks(cmd, programData, log)
+		if err != nil {
+			log.Error("Invalid arguments", "err", err)
+
+			os.Exit(1)
+		}
+
+		events, err := parsePresses(press, hold)
+		if err != nil {
+			log.Error("Invalid --press", "err", err)
+
+			os.Exit(1)
+		}
+
+		report, err := quirks.Detect(programData, quirks.Options{
+			Frames: frames,
+			Seed:   seed,
+			Base:   base,
+			Events: events,
+		})
+		if err != nil {
+			log.Error("Could not run ROM", "err", err)
+
+			os.Exit(1)
+		}
+
+		printQuirksReport(report)
+	},
+}
+
+func printQuirksReport(report quirks.Report) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(w, "QUIRK\tPROFILE\tRECOMMENDED\tDIVERGES AT\tOPCODES")
+
+	for _, d := range report.Divergences {
+		at := "never"
+		if d.Frame >= 0 {
+			at = fmt.Sprintf("frame %d", d.Frame)
+		}
+
+		opcodes := []string{}
+		for _, e := range d.Executions {
+			opcodes = append(opcodes, fmt.Sprintf("%04X@%03X", e.Opcode, e.PC))
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			d.Quirk,
+			onOff(report.Base.Get(d.Quirk)),
+			onOff(report.Recommended.Get(d.Quirk)),
+			at,
+			strings.Join(opcodes, " "),
+		)
+	}
+
+	w.Flush()
+
+	for _, r := range report.Runs {
+		if r.Quirks == report.Recommended && r.Fault != nil {
+			fmt.Printf("\nEvery permutation faults, the last one with: %v\n", r.Fault)
+		}
+	}
+
+	fmt.Printf("\nRecommended: --quirks %s\n", report.Recommended)
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+
+	return "off"
+}
+
+// parsePresses parses a comma separated list of frame:key items, ex: 60:5,90:A.
+// Every key is held for hold frames.
+func parsePresses(spec string, hold int) ([]headless.Event, error) {
+	events := []headless.Event{}
+
+	for _, item := range strings.Split(spec, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+
+		f, k, found := strings.Cut(item, ":")
+		if !found {
+			return nil, fmt.Errorf("expected frame:key, got %q", item)
+		}
+
+		frame, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return nil, fmt.Errorf("invalid frame in %q: %w", item, err)
+		}
+
+		key, err := strconv.ParseUint(strings.TrimSpace(k), 16, 4)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key in %q: %w", item, err)
+		}
+
+		events = append(events,
+			headless.Event{Frame: frame, Key: byte(key), Pressed: true},
+			headless.Event{Frame: frame + hold, Key: byte(key), Pressed: false},
+		)
+	}
+
+	return events, nil
+}
+
+func init() {
+	rootCmd.AddCommand(quirksCmd)
+
+	addLogFlags(quirksCmd)
+	quirksCmd.Flags().Int("frames", 600, "Frames to run under every permutation")
+	quirksCmd.Flags().Int64("seed", 1, "Seed for random numbers and random input")
+	quirksCmd.Flags().String("press", "", "Scripted input as frame:key items, ex: 60:5,90:A (random input when empty)")
+	quirksCmd.Flags().Int("hold", 4, "Frames every scripted key is held for")
+	addProfileFlags(quirksCmd)
+}
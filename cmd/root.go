@@ -15,13 +15,15 @@ import (
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "zamorak",
-	Short: "A brief description of your application",
-	Long: `A longer description that spans multiple lines and likely contains
-examples and usage of using your application. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+	Short: "A CHIP-8 emulator",
+	Long: `zamorak runs CHIP-8 programs in a window or in the terminal, and headlessly
+to test them, trace them and capture screenshots, clips and audio. Settings
+come from flags, the environment and a config file, see zamorak config. Ex:
+
+zamorak run pong.ch8
+zamorak run --frontend tty pong.ch8
+zamorak test tests/
+zamorak selftest`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
@@ -41,11 +43,7 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.zamorak.yaml)")
-
-	// Cobra also supports local flags, which will only run
-	// when this action is called directly.
-	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	rootCmd.PersistentFlags().String("config", "", "config file (default is $ZAMORAK_CONFIG or $HOME/.zamorak.yaml)")
 }
 
 
@@ -2,12 +2,30 @@ package cmd
 
 import (
 	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 
 	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/otaviohenrique/zamorak/pkg/beeper"
+	"github.com/otaviohenrique/zamorak/pkg/capture"
+	"github.com/otaviohenrique/zamorak/pkg/display"
+	"github.com/otaviohenrique/zamorak/pkg/emulator"
 	"github.com/otaviohenrique/zamorak/pkg/engine"
+	"github.com/otaviohenrique/zamorak/pkg/headless"
 	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+	"github.com/otaviohenrique/zamorak/pkg/keymap"
 	"github.com/otaviohenrique/zamorak/pkg/logger"
+	"github.com/otaviohenrique/zamorak/pkg/palette"
+	"github.com/otaviohenrique/zamorak/pkg/recorder"
+	"github.com/otaviohenrique/zamorak/pkg/trace"
+	"github.com/otaviohenrique/zamorak/pkg/tty"
 	"github.com/spf13/cobra"
 )
 
@@ -23,33 +41,551 @@ zamorak run /path/to/rom`,
 	Run: func(cmd *cobra.Command, args []string) {
 		filePath := args[0]
 
-		logLevel, _ := cmd.Flags().GetString("log-level")
-
 		programData, err := os.ReadFile(filePath)
 
 		if err != nil {
 			panic(err)
 		}
 
-		log := logger.NewLogger(logLevel)
+		if err := applyConfig(cmd, filePath, programData); err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid config:", err)
+
+			os.Exit(1)
+		}
+
+		tracePath, _ := cmd.Flags().GetString("trace")
+		recorderSize, _ := cmd.Flags().GetInt("flight-recorder")
+		dumpDir, _ := cmd.Flags().GetString("dump-dir")
+		keymapSpec, _ := cmd.Flags().GetString("keymap")
+		touchPlacement, _ := cmd.Flags().GetString("touch-keypad")
+		touchLayout, _ := cmd.Flags().GetString("touch-layout")
+		touchOpacity, _ := cmd.Flags().GetFloat64("touch-opacity")
+		hotkeySpec, _ := cmd.Flags().GetString("hotkeys")
+		muted, _ := cmd.Flags().GetBool("mute")
+		pauseOnBlur, _ := cmd.Flags().GetBool("pause-on-blur")
+		captureDir, _ := cmd.Flags().GetString("capture-dir")
+		ipf, _ := cmd.Flags().GetInt("ipf")
+		speed, _ := cmd.Flags().GetFloat64("speed")
+		fastForwardSpeed, _ := cmd.Flags().GetFloat64("fast-forward-speed")
+		benchmark, _ := cmd.Flags().GetBool("benchmark")
+		frames, _ := cmd.Flags().GetInt("frames")
+		showOSD, _ := cmd.Flags().GetBool("osd")
+		paletteSpec, _ := cmd.Flags().GetString("palette")
+		filterMode, _ := cmd.Flags().GetString("filter")
+		filterStrength, _ := cmd.Flags().GetFloat64("filter-strength")
+		shaderSpec, _ := cmd.Flags().GetString("shaders")
+		scale, _ := cmd.Flags().GetInt("scale")
+		fullscreen, _ := cmd.Flags().GetBool("fullscreen")
+		borderless, _ := cmd.Flags().GetBool("borderless")
+		resizable, _ := cmd.Flags().GetBool("resizable")
+		rotation, _ := cmd.Flags().GetInt("rotation")
+		headlessRun, _ := cmd.Flags().GetBool("headless")
+		screenshotAt, _ := cmd.Flags().GetInt("screenshot-at-frame")
+		screenshotScale, _ := cmd.Flags().GetInt("screenshot-scale")
+		screenshotStdout, _ := cmd.Flags().GetBool("screenshot-stdout")
+		recordPath, _ := cmd.Flags().GetString("record")
+		recordFormat, _ := cmd.Flags().GetString("record-format")
+		recordScale, _ := cmd.Flags().GetInt("record-scale")
+		recordSkip, _ := cmd.Flags().GetInt("record-skip")
+		videoOut, _ := cmd.Flags().GetString("video-out")
+		audioOut, _ := cmd.Flags().GetString("audio-out")
+		videoScale, _ := cmd.Flags().GetInt("video-scale")
+		wavPath, _ := cmd.Flags().GetString("wav")
+		volume, _ := cmd.Flags().GetFloat64("volume")
+		beepFrequency, _ := cmd.Flags().GetFloat64("beep-frequency")
+		seed, _ := cmd.Flags().GetInt64("seed")
+		frontend, _ := cmd.Flags().GetString("frontend")
+		ttyRenderer, _ := cmd.Flags().GetString("tty-renderer")
+		ttyColor, _ := cmd.Flags().GetBool("tty-color")
+		ttyKeyTimeout, _ := cmd.Flags().GetDuration("tty-key-timeout")
+
+		// screenshots written to stdout must not be mixed with logs, nor
+		// the game drawn by the terminal frontends, which hold them while
+		// it runs when stderr is the same terminal
+		var logOutput io.Writer
+		heldLogs := logger.NewHeld(os.Stderr, logger.HELD_LOG_SIZE)
+
+		switch {
+		case frontend != "window":
+			logOutput = heldLogs
+		case screenshotStdout:
+			logOutput = os.Stderr
+		}
+
+		log, logCloser, err := newLogger(cmd, logOutput)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid log flags:", err)
+
+			os.Exit(1)
+		}
+		defer logCloser.Close()
+
+		platform, quirks, err := resolveQuirks(cmd, programData, log)
+		if err != nil {
+			log.Error("Invalid arguments", "err", err)
+
+			os.Exit(1)
+		}
+
+		if recorderSize < 0 {
+			log.Error("Invalid arguments", "err", "--flight-recorder must not be negative")
+
+			os.Exit(1)
+		}
+
+		if ipf <= 0 || speed <= 0 || fastForwardSpeed <= 0 {
+			log.Error("Invalid arguments", "err", "--ipf, --speed and --fast-forward-speed must be positive")
+
+			os.Exit(1)
+		}
+
+		if scale <= 0 || screenshotScale <= 0 || videoScale <= 0 {
+			log.Error("Invalid arguments", "err", "--scale, --screenshot-scale and --video-scale must be positive")
+
+			os.Exit(1)
+		}
+
+		if screenshotAt > 0 && !headlessRun {
+			log.Error("Invalid arguments", "err", "--screenshot-at-frame needs --headless")
+
+			os.Exit(1)
+		}
+
+		// a benchmark only counts instructions, recording them would skew it
+		if benchmark && (tracePath != "" || cmd.Flags().Changed("flight-recorder") || recordPath != "" || videoOut != "" || audioOut != "" || wavPath != "") {
+			log.Error("Invalid arguments", "err", "--benchmark does not trace, record or stream, drop --trace, --flight-recorder, --record, --video-out, --audio-out and --wav")
+
+			os.Exit(1)
+		}
+
+		colors, err := palette.Parse(paletteSpec)
+		if err != nil {
+			log.Error("Invalid arguments", "err", err)
+
+			os.Exit(1)
+		}
+
+		filter, err := display.NewFilter(filterMode, filterStrength, 64, 32)
+		if err != nil {
+			log.Error("Invalid arguments", "err", err)
+
+			os.Exit(1)
+		}
+
+		if filter.Mode() == "none" {
+			filter = nil
+		}
+
+		if recordFormat != "gif" && recordFormat != "apng" {
+			log.Error("Invalid arguments", "err", "--record-format must be gif or apng")
+
+			os.Exit(1)
+		}
+
+		if frontend != "window" && frontend != "tty" && frontend != "sixel" && frontend != "kitty" {
+			log.Error("Invalid arguments", "err", "--frontend must be window, tty, sixel or kitty")
+
+			os.Exit(1)
+		}
+
+		if frontend != "window" && !headlessRun && (recordPath != "" || videoOut != "" || audioOut != "" || wavPath != "") {
+			log.Error("Invalid arguments", "err", "clips and streams are not recorded by the terminal frontends")
+
+			os.Exit(1)
+		}
+
+		if benchmark {
+			if err := runBenchmark(log, quirks, filePath, programData, ipf, frames); err != nil {
+				log.Error("Could not run benchmark", "err", err)
+
+				os.Exit(1)
+			}
+
+			return
+		}
+
+		var clip *capture.Clip
+		if recordPath != "" {
+			clip, err = capture.NewClip(recordPath, colors, recordScale, recordSkip)
+			if err != nil {
+				log.Error("Invalid arguments", "err", err)
+
+				os.Exit(1)
+			}
+		}
+
+		if volume < 0 || volume > 1 || beepFrequency <= 0 {
+			log.Error("Invalid arguments", "err", "--volume must be between 0 and 1 and --beep-frequency positive")
+
+			os.Exit(1)
+		}
+
+		if wavPath != "" {
+			if audioOut != "" {
+				log.Error("Invalid arguments", "err", "--wav and --audio-out both write the beeper, use one")
+
+				os.Exit(1)
+			}
+
+			audioOut = wavPath
+		}
+
+		var streams *capture.Streams
+		if videoOut != "" || audioOut != "" {
+			streams, err = capture.OpenStreams(videoOut, audioOut, 64, 32, videoScale)
+			if err != nil {
+				log.Error("Could not open streams", "err", err)
+
+				os.Exit(1)
+			}
+
+			if b := streams.Beeper(); b != nil {
+				b.SetFrequency(beepFrequency)
+				b.SetVolume(volume * beeper.DEFAULT_VOLUME)
+			}
+		}
+
+		if headlessRun {
+			opts := headlessOptions{
+				ipf:              ipf,
+				seed:             seed,
+				frames:           frames,
+				palette:          colors,
+				filter:           filter,
+				screenshotFrame:  screenshotAt,
+				screenshotScale:  screenshotScale,
+				screenshotStdout: screenshotStdout,
+				captureDir:       captureDir,
+				clip:             clip,
+				streams:          streams,
+				recorderSize:     recorderSize,
+				dumpDir:          dumpDir,
+				tracePath:        tracePath,
+			}
+
+			if err := runHeadless(log, quirks, filePath, programData, opts); err != nil {
+				log.Error("Headless run failed", "err", err)
+
+				os.Exit(1)
+			}
+
+			return
+		}
+
+		// the window reads key positions, the terminal the characters typed
+		parseKeymap := keymap.ParsePositions
+		if frontend != "window" {
+			parseKeymap = keymap.Parse
+		}
+
+		keys, err := parseKeymap(keymapSpec)
+		if err != nil {
+			log.Error("Invalid arguments", "err", err)
+
+			os.Exit(1)
+		}
+
+		hotkeys, err := engine.ParseHotkeys(hotkeySpec)
+		if err != nil {
+			log.Error("Invalid arguments", "err", err)
+
+			os.Exit(1)
+		}
+
+		// the terminal frontends have no hotkeys
+		if frontend == "window" {
+			if err := hotkeys.CheckKeymap(keys); err != nil {
+				log.Error("Invalid arguments", "err", err)
+
+				os.Exit(1)
+			}
+		}
+
+		if frontend != "window" {
+			term := headless.NewRuntime()
+
+			emu, err := emulator.New(log, quirks, filePath, programData, term)
+			if err != nil {
+				log.Error("Could not load program", "err", err)
+
+				os.Exit(1)
+			}
+
+			if cmd.Flags().Changed("seed") {
+				emu.Seed(seed)
+			}
+
+			emu.SetIPF(ipf)
+			emu.SetSpeed(speed)
+
+			closeTrace, err := instrumentEmulator(emu, programData, quirks, recorderSize, dumpDir, tracePath, log)
+			if err != nil {
+				log.Error("Could not create trace", "err", err)
+
+				os.Exit(1)
+			}
+			defer closeTrace()
+
+			t := tty.New(emu, term)
+			t.SetKeymap(keys)
+			t.SetPalette(colors, ttyColor)
+			t.SetKeyTimeout(ttyKeyTimeout)
+
+			if err := t.SetRenderer(ttyRenderer); err != nil {
+				log.Error("Invalid arguments", "err", err)
+
+				os.Exit(1)
+			}
+
+			// the renderer is the fallback on terminals without graphics
+			if frontend != "tty" {
+				if err := t.SetGraphics(frontend); err != nil {
+					log.Error("Invalid arguments", "err", err)
+
+					os.Exit(1)
+				}
+			}
+
+			if tty.IsTerminal(os.Stderr) {
+				heldLogs.Hold()
+			}
+
+			err = t.Run()
+			heldLogs.Release()
+
+			if err != nil {
+				log.Error("Terminal frontend failed", "err", err)
+			}
+
+			return
+		}
 
 		runtime := engine.NewRuntime(64, 32, GameSound, log)
+		runtime.SetScale(scale)
+
+		if err := runtime.SetKeymap(keys); err != nil {
+			log.Error("Invalid arguments", "err", err)
+
+			os.Exit(1)
+		}
+
+		runtime.SetPalette(colors)
+		runtime.SetFilter(filter)
+
+		shaders, err := engine.ParseShaderChain(shaderSpec)
+		if err != nil {
+			log.Error("Invalid arguments", "err", err)
+
+			os.Exit(1)
+		}
+
+		runtime.SetShaders(shaders)
+
+		if err := runtime.SetRotation(rotation); err != nil {
+			log.Error("Invalid arguments", "err", err)
+
+			os.Exit(1)
+		}
+
+		if touchPlacement != "off" {
+			touch, err := engine.NewTouchKeypad(touchPlacement, touchLayout, touchOpacity)
+			if err != nil {
+				log.Error("Invalid arguments", "err", err)
+
+				os.Exit(1)
+			}
+
+			runtime.SetTouchKeypad(touch)
+		}
+
+		emu, err := emulator.New(log, quirks, filePath, programData, runtime)
+		if err != nil {
+			log.Error("Could not load program", "err", err)
+
+			os.Exit(1)
+		}
+
+		if cmd.Flags().Changed("seed") {
+			emu.Seed(seed)
+		}
+
+		emu.SetIPF(ipf)
+		emu.SetSpeed(speed)
+		emu.SetFastForwardSpeed(fastForwardSpeed)
+
+		closeTrace, err := instrumentEmulator(emu, programData, quirks, recorderSize, dumpDir, tracePath, log)
+		if err != nil {
+			log.Error("Could not create trace", "err", err)
+
+			os.Exit(1)
+		}
+		defer closeTrace()
+
+		runtime.Attach(emu, hotkeys)
+		runtime.SetMuted(muted)
+		runtime.SetPauseOnBlur(pauseOnBlur)
+		runtime.SetCaptureDir(captureDir)
+		runtime.SetScreenshotScale(screenshotScale)
+		runtime.SetScreenshotStdout(screenshotStdout)
+		runtime.SetClipOptions("."+recordFormat, recordScale, recordSkip)
+		runtime.SetStreams(streams)
+		runtime.SetVolume(volume)
+
+		if recordPath != "" {
+			if err := runtime.StartRecording(recordPath); err != nil {
+				log.Error("Invalid arguments", "err", err)
 
-		inter := interpreter.NewChip8(log)
+				os.Exit(1)
+			}
+		}
+		runtime.OSD().SetProfile(platform.String())
+		runtime.OSD().SetVisible(showOSD)
+
+		screenWidth, screenHeight := runtime.ScreenSize()
+		ebiten.SetWindowSize(screenWidth, screenHeight)
+		ebiten.SetWindowDecorated(!borderless)
+		ebiten.SetFullscreen(fullscreen)
 
-		go inter.Interpret(runtime, programData)
+		if resizable {
+			ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+		}
 
-		ebiten.SetWindowSize(640, 480)
-		ebiten.SetWindowTitle("Hello, CHIP-8!")
+		runtime.SetTitle(fmt.Sprintf("zamorak - %s (%s)", filepath.Base(filePath), platform))
 
 		if err := ebiten.RunGame(runtime); err != nil {
 			panic(err)
 		}
+
+		if streams != nil {
+			if err := streams.Close(); err != nil {
+				log.Error("Could not close streams", "err", err)
+			}
+		}
+
+		if runtime.Recording() {
+			if path, err := runtime.StopRecording(); err != nil {
+				log.Error("Could not save clip", "err", err)
+			} else {
+				log.Info("Clip saved", "path", path)
+			}
+		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(runCmd)
 
-	runCmd.Flags().StringP("log-level", "l", "INFO", "Log Level")
+	addLogFlags(runCmd)
+	runCmd.Flags().String("trace", "", "Record every instruction to this file, as JSON lines if it ends in .jsonl, in binary otherwise")
+	runCmd.Flags().Int("flight-recorder", recorder.DEFAULT_SIZE, "Instructions to keep in memory and dump on a fault, a halt or SIGQUIT, 0 keeps only the machine state")
+	runCmd.Flags().String("dump-dir", ".", "Directory flight recorder dumps are written to")
+	runCmd.Flags().StringP("keymap", "k", keymap.DEFAULT_PRESET, "Keyboard layout: "+strings.Join(keymap.Presets(), ", ")+", optionally followed by overrides, ex: azerty,0=Space/X. "+strings.Join(keymap.LABEL_PRESETS, ", ")+" and their overrides name key labels, read where those keys are on that layout. Per-ROM keymaps go in the roms section of the config file")
+	runCmd.Flags().String("palette", palette.DEFAULT_PRESET, "Screen colors: "+strings.Join(palette.Presets(), ", ")+", optionally followed by overrides, ex: amber,1=#FF8800, or a list of colors starting with the background, ex: #000000,#33FF66")
+	runCmd.Flags().String("filter", "none", "Anti-flicker filter: "+strings.Join(display.FILTERS, ", "))
+	runCmd.Flags().Float64("filter-strength", display.DEFAULT_FILTER_STRENGTH, "Strength of the anti-flicker filter, from 0 to 1")
+	runCmd.Flags().String("shaders", "", "Post-processing shaders, applied in order: "+strings.Join(engine.Shaders(), ", ")+" or .kage files, with parameters, ex: crt:curvature=0.2,scanlines")
+	runCmd.Flags().String("frontend", "window", "Where the game is shown: window, tty to draw it with characters in the terminal, or sixel or kitty to draw it with pixels in terminals supporting them")
+	runCmd.Flags().String("tty-renderer", tty.RENDERERS[0], "How the terminal frontend draws pixels: "+strings.Join(tty.RENDERERS, ", "))
+	runCmd.Flags().Bool("tty-color", false, "Draw in the colors of the palette in the terminal, needs 24 bit color")
+	runCmd.Flags().Duration("tty-key-timeout", tty.DEFAULT_KEY_TIMEOUT, "Release a key in the terminal when it was not repeated for this long")
+	runCmd.Flags().Int("scale", engine.SCREEN_SCALE, "The game is drawn this many times the screen resolution, in whole pixels, and the window opens at that size")
+	runCmd.Flags().Bool("fullscreen", false, "Start in full screen")
+	runCmd.Flags().Bool("borderless", false, "Open the window without a title bar and borders")
+	runCmd.Flags().Bool("resizable", false, "Let the window be resized, the screen keeps its aspect ratio")
+	runCmd.Flags().Int("rotation", 0, "Turn the screen clockwise by 0, 90, 180 or 270 degrees")
+	runCmd.Flags().String("touch-keypad", "off", "On-screen keypad for mouse and touch input: "+strings.Join(engine.TOUCH_PLACEMENTS, ", "))
+	runCmd.Flags().String("touch-layout", "vip", "Key order of the on-screen keypad: vip or hex")
+	runCmd.Flags().Float64("touch-opacity", 0.8, "Opacity of the on-screen keypad, from 0 to 1")
+	runCmd.Flags().String("hotkeys", "", "Rebind emulator hotkeys ("+strings.Join(engine.HotkeyActions(), ", ")+"), ex: pause=P,reset=F5")
+	runCmd.Flags().Bool("mute", false, "Start with the beeper muted")
+	runCmd.Flags().Bool("pause-on-blur", false, "Pause while the window does not have focus")
+	runCmd.Flags().String("capture-dir", ".", "Directory screenshots and clips are saved to")
+	runCmd.Flags().Int("ipf", interpreter.INSTRUCTIONS_PER_FRAME, "Instructions executed per frame")
+	runCmd.Flags().Float64("speed", 1, "Emulation speed multiplier, ex: 0.5 for half speed")
+	runCmd.Flags().Float64("fast-forward-speed", emulator.FAST_FORWARD_SPEED, "Speed multiplier while the fast-forward hotkey is held")
+	runCmd.Flags().Bool("benchmark", false, "Run without a window, as fast as possible, and report the emulation speed")
+	runCmd.Flags().Int("frames", 3600, "Frames to run with --benchmark or --headless")
+	runCmd.Flags().Bool("headless", false, "Run without a window, as fast as possible, for --frames frames or until --screenshot-at-frame")
+	runCmd.Flags().Int("screenshot-at-frame", 0, "Take a screenshot once this frame has run and stop, with --headless")
+	runCmd.Flags().Int("screenshot-scale", 1, "Screenshots are this many times the screen resolution, 1 keeps it native")
+	runCmd.Flags().String("record", "", "Record gameplay from the start into this .gif or .png (APNG) file, written on exit")
+	runCmd.Flags().String("record-format", "gif", "Format of clips recorded with the hotkey: gif or apng")
+	runCmd.Flags().Int("record-scale", 4, "Clips are this many times the screen resolution")
+	runCmd.Flags().Int("record-skip", 1, "Keep one frame out of this many in clips, 2 halves their size")
+	runCmd.Flags().String("video-out", "", "Write every frame to this file or named pipe as a YUV4MPEG2 stream")
+	runCmd.Flags().String("audio-out", "", "Write the beeper to this file or named pipe as a WAV stream, in sync with --video-out")
+	runCmd.Flags().String("wav", "", "Export the beeper to this WAV file, with --headless it takes no audio device and is the same on every run")
+	runCmd.Flags().Float64("volume", 1, "Beeper volume, from 0 to 1")
+	runCmd.Flags().Float64("beep-frequency", beeper.DEFAULT_FREQUENCY, "Pitch of the beep in exported and streamed sound, in Hz")
+	runCmd.Flags().Int64("seed", 0, "Seed of the random numbers, headless runs always use it so they are the same every time")
+	runCmd.Flags().Int("video-scale", 1, "The video stream is this many times the screen resolution")
+	runCmd.Flags().Bool("screenshot-stdout", false, "Write screenshots to stdout as PNG instead of the capture directory, logs then go to stderr")
+	runCmd.Flags().Bool("osd", false, "Start with the on-screen display of speed and machine state shown")
+	addProfileFlags(runCmd)
+}
+
+// instrumentEmulator installs the flight recorder and, when tracePath is set,
+// the tracer on an emulator, and supervises it. The returned function writes
+// the end of the trace.
+func instrumentEmulator(emu *emulator.Emulator, programData []byte, quirks interpreter.Quirks, recorderSize int, dumpDir string, tracePath string, log *slog.Logger) (func(), error) {
+	rec := recorder.NewRecorder(recorderSize, emu.Path(), programData, quirks)
+	emu.AddHook(rec.Hook)
+
+	closeTrace := func() {}
+
+	if tracePath != "" {
+		tracer, err := trace.Create(tracePath)
+		if err != nil {
+			return nil, err
+		}
+
+		emu.AddHook(tracer.Hook)
+
+		closeTrace = func() {
+			if err := tracer.Close(); err != nil {
+				log.Error("Could not write trace", "err", err)
+			}
+		}
+	}
+
+	superviseEmulator(emu, rec, dumpDir, log)
+
+	return closeTrace, nil
+}
+
+// superviseEmulator logs why the program stopped and writes a flight
+// recorder dump when it faults, halts or the process receives SIGQUIT. The
+// window stays open after a fault so the last frame can be inspected.
+func superviseEmulator(emu *emulator.Emulator, rec *recorder.Recorder, dumpDir string, log *slog.Logger) {
+	save := func(d *recorder.Dump) {
+		path, err := d.Save(dumpDir)
+		if err != nil {
+			log.Error("Could not write flight recorder dump", "err", err)
+
+			return
+		}
+
+		log.Info("Flight recorder dump written", "reason", d.Reason, "path", path)
+	}
+
+	emu.OnStop(func(err error) {
+		if errors.Is(err, interpreter.ErrHalted) {
+			log.Info("Program halted", "pc", fmt.Sprintf("%03x", emu.Chip8().PC()))
+		} else {
+			log.Error("Program crashed the interpreter", "err", err)
+		}
+
+		save(rec.Snapshot(emu.Chip8(), err.Error()))
+	})
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGQUIT)
+
+	go func() {
+		for range quit {
+			emu.Queue(func(c *interpreter.Chip8) {
+				save(rec.Snapshot(c, "SIGQUIT"))
+			})
+		}
+	}()
 }
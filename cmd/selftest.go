@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/otaviohenrique/zamorak/pkg/selftest"
+	"github.com/spf13/cobra"
+)
+
+// selftestCmd represents the selftest command
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Run the bundled conformance test ROMs",
+	Long: `Run the bundled opcode, flags, quirks and keypad test ROMs headlessly on
+every variant and compare the final screens with known good ones. Ex:
+
+zamorak selftest
+zamorak selftest --verbose`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		verbose, _ := cmd.Flags().GetBool("verbose")
+		writeGolden, _ := cmd.Flags().GetString("write-golden")
+
+		results := selftest.Run(selftest.Cases())
+
+		if writeGolden != "" {
+			f, err := os.Create(writeGolden)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			defer f.Close()
+
+			if err := selftest.WriteGolden(f, results); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+
+			return
+		}
+
+		if !printSelftestTable(results, verbose) {
+			os.Exit(1)
+		}
+	},
+}
+
+// printSelftestTable prints one row per case and one column per variant, and
+// reports whether every case passed.
+func printSelftestTable(results []selftest.Result, verbose bool) bool {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+
+	fmt.Fprint(w, "GROUP\tTEST")
+	for _, variant := range selftest.VARIANTS {
+		fmt.Fprintf(w, "\t%s", variant)
+	}
+	fmt.Fprintln(w)
+
+	passed, failed := 0, []selftest.Result{}
+
+	for i := 0; i < len(results); i += len(selftest.VARIANTS) {
+		fmt.Fprintf(w, "%s\t%s", results[i].Case.Group, results[i].Case.Name)
+
+		for _, r := range results[i : i+len(selftest.VARIANTS)] {
+			if r.Passed() {
+				passed++
+				fmt.Fprint(w, "\tPASS")
+			} else {
+				failed = append(failed, r)
+				fmt.Fprint(w, "\tFAIL")
+			}
+		}
+
+		fmt.Fprintln(w)
+	}
+
+	w.Flush()
+
+	fmt.Printf("\n%d passed, %d failed\n", passed, len(failed))
+
+	if verbose {
+		for _, r := range failed {
+			fmt.Printf("\n%s: got %016x, want %016x", r.Key(), r.Hash, r.Golden)
+			if r.Err != nil {
+				fmt.Printf(", %v", r.Err)
+			}
+
+			// cases that could not be loaded have no screen
+			if r.Screen != nil {
+				fmt.Printf("\n%s", r.Screen)
+			} else {
+				fmt.Println()
+			}
+		}
+	}
+
+	return len(failed) == 0
+}
+
+func init() {
+	rootCmd.AddCommand(selftestCmd)
+
+	selftestCmd.Flags().BoolP("verbose", "v", false, "Show the screen of every failed test")
+	selftestCmd.Flags().String("write-golden", "", "Write the current screens as the new golden hashes to this file")
+}
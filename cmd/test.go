@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/otaviohenrique/zamorak/pkg/romtest"
+	"github.com/spf13/cobra"
+)
+
+// testCmd represents the test command
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Run ROM acceptance tests written in YAML",
+	Long: `Run every .yaml and .yml test under a directory headlessly. A test lists
+steps, ex:
+
+name: score goes up
+steps:
+  - load pong.ch8
+  - profile chip8
+  - seed 42
+  - press 5 at frame 10
+  - release 5 at frame 20
+  - run 120 frames
+  - expect V3 == 0x10
+  - expect memory[0x300..0x302] == 1 2 3
+  - expect screen matches golden.txt
+
+Paths are relative to the test file. Ex:
+
+zamorak test tests/
+zamorak test --junit report.xml tests/`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		junit, _ := cmd.Flags().GetString("junit")
+		update, _ := cmd.Flags().GetBool("update")
+
+		paths, err := romtest.Find(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		results := []romtest.Result{}
+		failed := 0
+
+		for _, path := range paths {
+			var r romtest.Result
+
+			// a file that cannot be parsed fails, the others still run
+			if t, err := romtest.Parse(path); err != nil {
+				r = romtest.Unparsed(path, err)
+			} else {
+				r = romtest.Run(t, update)
+			}
+
+			results = append(results, r)
+
+			if r.Passed() {
+				fmt.Printf("PASS  %s (%s)\n", r.Test.Name, path)
+			} else {
+				failed++
+				fmt.Printf("FAIL  %s (%s)\n      %v\n", r.Test.Name, path, r.Err)
+			}
+		}
+
+		fmt.Printf("\n%d passed, %d failed\n", len(results)-failed, failed)
+
+		if junit != "" {
+			f, err := os.Create(junit)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+
+			err = romtest.WriteJUnit(f, args[0], results)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(testCmd)
+
+	testCmd.Flags().String("junit", "", "Write a JUnit XML report to this file")
+	testCmd.Flags().Bool("update", false, "Write golden screens instead of comparing against them")
+}
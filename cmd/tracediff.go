@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/otaviohenrique/zamorak/pkg/trace"
+	"github.com/spf13/cobra"
+)
+
+// tracediffCmd represents the tracediff command
+var tracediffCmd = &cobra.Command{
+	Use:   "tracediff",
+	Short: "Find where two execution traces diverge",
+	Long: `Compare two execution traces, recorded with zamorak run --trace or converted
+from another emulator's log to JSON lines, and report the first instruction
+where they differ. Ex:
+
+zamorak tracediff ours.trace theirs.jsonl`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		context, _ := cmd.Flags().GetInt("context")
+
+		a, closeA, err := trace.Open(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer closeA.Close()
+
+		b, closeB, err := trace.Open(args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer closeB.Close()
+
+		d, matched, err := trace.Diff(a, b, context)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if d == nil {
+			fmt.Printf("Traces match, %d instructions compared\n", matched)
+			return
+		}
+
+		fmt.Printf("Traces diverge at instruction %d: %s\n\n", d.Index, strings.Join(d.Fields, ", "))
+
+		for _, p := range d.Context {
+			fmt.Printf("  %s\n", p.A)
+		}
+
+		printDivergent("<", args[0], d.A)
+		printDivergent(">", args[1], d.B)
+
+		os.Exit(1)
+	},
+}
+
+func printDivergent(marker string, name string, r *trace.Record) {
+	if r == nil {
+		fmt.Printf("%s (%s ended)\n", marker, name)
+		return
+	}
+
+	fmt.Printf("%s %s\n", marker, r)
+}
+
+func init() {
+	rootCmd.AddCommand(tracediffCmd)
+
+	tracediffCmd.Flags().IntP("context", "C", 5, "Matching instructions to show before the divergence")
+}
@@ -0,0 +1,82 @@
+package beeper
+
+import "math"
+
+var (
+	DEFAULT_SAMPLE_RATE = 48000
+
+	// pitch of the beep, the COSMAC VIP had no fixed one
+	DEFAULT_FREQUENCY = 440.0
+
+	// peak of the square wave, from 0 to 1
+	DEFAULT_VOLUME = 0.25
+
+	// CHIP-8 frames per second
+	FRAME_RATE = 60
+)
+
+// Beeper synthesizes the CHIP-8 beeper, a square wave sounding while the
+// sound timer is not zero, one frame at a time. Its output only depends on
+// the frames it is given, so the same run always gives the same samples.
+// XO-CHIP audio patterns are not played, the interpreter runs CHIP-8
+// programs only.
+type Beeper struct {
+	rate    int
+	freq    float64
+	volume  float64
+	phase   float64 // position in the current period, from 0 to 1
+	samples int     // samples produced so far
+	frames  int     // frames produced so far
+}
+
+func New(rate int) *Beeper {
+	b := new(Beeper)
+
+	b.rate = rate
+	b.freq = DEFAULT_FREQUENCY
+	b.volume = DEFAULT_VOLUME
+
+	return b
+}
+
+// Rate returns the sample rate.
+func (b *Beeper) Rate() int {
+	return b.rate
+}
+
+// SetFrequency sets the pitch of the beep, in Hz.
+func (b *Beeper) SetFrequency(freq float64) {
+	b.freq = freq
+}
+
+// SetVolume sets the peak of the square wave, from 0 to 1.
+func (b *Beeper) SetVolume(volume float64) {
+	b.volume = volume
+}
+
+// Frame returns the mono samples of one 60th of a second, a beep if on and
+// silence otherwise. Frames are rate/60 samples long, rounded such that the
+// samples never drift from the frames.
+func (b *Beeper) Frame(on bool) []int16 {
+	b.frames++
+	n := b.frames*b.rate/FRAME_RATE - b.samples
+	b.samples += n
+
+	samples := make([]int16, n)
+	peak := int16(math.Round(b.volume * math.MaxInt16))
+
+	for i := range samples {
+		if on {
+			if b.phase < 0.5 {
+				samples[i] = peak
+			} else {
+				samples[i] = -peak
+			}
+		}
+
+		b.phase += b.freq / float64(b.rate)
+		b.phase -= math.Floor(b.phase)
+	}
+
+	return samples
+}
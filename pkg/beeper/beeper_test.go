@@ -0,0 +1,68 @@
+package beeper
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFrameLengths(t *testing.T) {
+	tests := []struct {
+		rate int
+		want []int // samples of the first frames
+	}{
+		{48000, []int{800, 800, 800}},
+		{44100, []int{735, 735, 735}},
+		{22050, []int{367, 368, 367, 368}},
+	}
+
+	for _, tt := range tests {
+		b := New(tt.rate)
+
+		for i, want := range tt.want {
+			if got := len(b.Frame(false)); got != want {
+				t.Errorf("rate %d, frame %d has %d samples, want %d", tt.rate, i, got, want)
+			}
+		}
+	}
+}
+
+func TestFramesDoNotDrift(t *testing.T) {
+	b := New(22050)
+
+	total := 0
+	for i := 0; i < 60*10; i++ {
+		total += len(b.Frame(i%2 == 0))
+	}
+
+	if total != 22050*10 {
+		t.Errorf("10 seconds have %d samples, want %d", total, 22050*10)
+	}
+}
+
+func TestSquareWave(t *testing.T) {
+	b := New(48000)
+	b.SetFrequency(750)
+	b.SetVolume(0.5)
+
+	peak := int16(math.Round(0.5 * math.MaxInt16))
+
+	samples := b.Frame(true)
+
+	// 64 samples per period, the first half up
+	for i, s := range samples[:64] {
+		want := peak
+		if i >= 32 {
+			want = -peak
+		}
+
+		if s != want {
+			t.Fatalf("sample %d = %d, want %d", i, s, want)
+		}
+	}
+
+	for i, s := range b.Frame(false) {
+		if s != 0 {
+			t.Fatalf("silent frame sample %d = %d, want 0", i, s)
+		}
+	}
+}
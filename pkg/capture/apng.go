@@ -0,0 +1,147 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image/png"
+	"io"
+)
+
+// pngSignature starts every PNG file.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// writeAPNG writes the clip as an animated PNG. The standard library only
+// writes still images, so every frame is encoded on its own and its image
+// data moved into the frame chunks of a single file. APNG delays are
+// fractions, so frames last exactly their number of 60ths of a second.
+func (c *Clip) writeAPNG(w io.Writer) error {
+	if _, err := w.Write(pngSignature); err != nil {
+		return err
+	}
+
+	seq := uint32(0)
+
+	for i, frame := range c.frames {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, c.scaled(frame)); err != nil {
+			return err
+		}
+
+		chunks, err := readChunks(buf.Bytes()[len(pngSignature):])
+		if err != nil {
+			return err
+		}
+
+		for _, ch := range chunks {
+			switch ch.kind {
+			case "IHDR", "PLTE", "tRNS":
+				if i > 0 {
+					continue
+				}
+
+				if err := writeChunk(w, ch.kind, ch.data); err != nil {
+					return err
+				}
+
+				// the animation control goes right after the header
+				if ch.kind == "IHDR" {
+					actl := make([]byte, 8)
+					binary.BigEndian.PutUint32(actl[0:], uint32(len(c.frames)))
+					binary.BigEndian.PutUint32(actl[4:], 0) // loop forever
+
+					if err := writeChunk(w, "acTL", actl); err != nil {
+						return err
+					}
+				}
+			case "IDAT":
+				if ch.first {
+					b := frame.Bounds()
+
+					fctl := make([]byte, 26)
+					binary.BigEndian.PutUint32(fctl[0:], seq)
+					binary.BigEndian.PutUint32(fctl[4:], uint32(b.Dx()*c.scale))
+					binary.BigEndian.PutUint32(fctl[8:], uint32(b.Dy()*c.scale))
+					binary.BigEndian.PutUint16(fctl[20:], uint16(c.delays[i]))
+					binary.BigEndian.PutUint16(fctl[22:], 60)
+					seq++
+
+					if err := writeChunk(w, "fcTL", fctl); err != nil {
+						return err
+					}
+				}
+
+				if i == 0 {
+					if err := writeChunk(w, "IDAT", ch.data); err != nil {
+						return err
+					}
+
+					continue
+				}
+
+				fdat := make([]byte, 4+len(ch.data))
+				binary.BigEndian.PutUint32(fdat, seq)
+				copy(fdat[4:], ch.data)
+				seq++
+
+				if err := writeChunk(w, "fdAT", fdat); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return writeChunk(w, "IEND", nil)
+}
+
+type chunk struct {
+	kind  string
+	data  []byte
+	first bool // first IDAT chunk of the image
+}
+
+// readChunks splits a PNG stream, without its signature, into chunks.
+func readChunks(b []byte) ([]chunk, error) {
+	var chunks []chunk
+
+	seenIDAT := false
+	for len(b) >= 12 {
+		n := binary.BigEndian.Uint32(b)
+		if uint64(len(b)) < 12+uint64(n) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		ch := chunk{kind: string(b[4:8]), data: b[8 : 8+n]}
+		if ch.kind == "IDAT" {
+			ch.first = !seenIDAT
+			seenIDAT = true
+		}
+
+		chunks = append(chunks, ch)
+		b = b[12+n:]
+	}
+
+	return chunks, nil
+}
+
+// writeChunk writes a chunk with its length and checksum.
+func writeChunk(w io.Writer, kind string, data []byte) error {
+	var head [8]byte
+	binary.BigEndian.PutUint32(head[:4], uint32(len(data)))
+	copy(head[4:], kind)
+
+	crc := crc32.NewIEEE()
+	crc.Write(head[4:])
+	crc.Write(data)
+
+	var tail [4]byte
+	binary.BigEndian.PutUint32(tail[:], crc.Sum32())
+
+	for _, b := range [][]byte{head[:], data, tail[:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package capture
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/otaviohenrique/zamorak/pkg/display"
+	"github.com/otaviohenrique/zamorak/pkg/palette"
+)
+
+// FileName names a capture of a ROM taken at t, ex:
+// zamorak-pong-20240102-150405.000.png for ext .png.
+func FileName(rom string, ext string, t time.Time) string {
+	name := strings.TrimSuffix(filepath.Base(rom), filepath.Ext(rom))
+
+	return fmt.Sprintf("zamorak-%s-%s%s", name, t.Format("20060102-150405.000"), ext)
+}
+
+// Render draws a framebuffer in the colors of a palette, every pixel scale x
+// scale times larger. With a filter the pixels are drawn as bright as the
+// filter says, otherwise as they are in the framebuffer.
+func Render(fb *display.Framebuffer, p palette.Palette, f *display.Filter, scale int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, fb.Width()*scale, fb.Height()*scale))
+
+	for row := 0; row < fb.Height(); row++ {
+		for col := 0; col < fb.Width(); col++ {
+			c := p.Background()
+
+			switch {
+			case f != nil:
+				c = Mix(p.Background(), p.Color(1), f.Intensity(col, row))
+			case fb.IsPixelSet(col, row):
+				c = p.Color(1)
+			}
+
+			for y := row * scale; y < (row+1)*scale; y++ {
+				for x := col * scale; x < (col+1)*scale; x++ {
+					img.SetRGBA(x, y, c)
+				}
+			}
+		}
+	}
+
+	return img
+}
+
+// Scale returns an image scale x scale times larger, without smoothing.
+func Scale(src *image.RGBA, scale int) *image.RGBA {
+	if scale == 1 {
+		return src
+	}
+
+	b := src.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, b.Dx()*scale, b.Dy()*scale))
+
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			img.SetRGBA(x, y, src.RGBAAt(b.Min.X+x/scale, b.Min.Y+y/scale))
+		}
+	}
+
+	return img
+}
+
+// Mix returns the color t of the way from a to b.
+func Mix(a, b color.RGBA, t float64) color.RGBA {
+	lerp := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*t + 0.5)
+	}
+
+	return color.RGBA{R: lerp(a.R, b.R), G: lerp(a.G, b.G), B: lerp(a.B, b.B), A: 0xFF}
+}
+
+// WritePNG encodes an image as PNG.
+func WritePNG(w io.Writer, img image.Image) error {
+	return png.Encode(w, img)
+}
+
+// SavePNG writes an image to a file of dir named after the ROM and the time,
+// and returns its path.
+func SavePNG(img image.Image, dir string, rom string) (string, error) {
+	path := filepath.Join(dir, FileName(rom, ".png", time.Now()))
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+
+	if err := WritePNG(f, img); err != nil {
+		f.Close()
+		return "", err
+	}
+
+	return path, f.Close()
+}
@@ -0,0 +1,216 @@
+package capture
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/otaviohenrique/zamorak/pkg/display"
+	"github.com/otaviohenrique/zamorak/pkg/palette"
+)
+
+var (
+	// formats a clip can be saved in, by file extension
+	CLIP_FORMATS = map[string]string{
+		".gif":  "gif",
+		".png":  "apng",
+		".apng": "apng",
+	}
+
+	// shades between the background and the first plane a filtered pixel can
+	// take in a clip
+	CLIP_SHADES = 16
+
+	// shortest GIF frame, in 100ths of a second: browsers play shorter
+	// delays as 10
+	MIN_GIF_DELAY = 2
+)
+
+// Clip records the frames of a game into an animated GIF or APNG. Frames are
+// kept at the screen resolution and scaled up when the clip is written, and a
+// frame that does not change the screen only makes the previous one last
+// longer.
+type Clip struct {
+	path    string
+	format  string
+	scale   int
+	skip    int // keep one frame out of skip
+	colors  color.Palette
+	frames  []*image.Paletted
+	delays  []int // how long every frame lasts, in 60ths of a second
+	counted int   // frames seen, kept or not
+}
+
+// NewClip records into a file whose extension, .gif, .png or .apng, says the
+// format. Every scale x scale block of the clip is a pixel of the screen, and
+// only one frame out of skip is kept, to make the clip smaller.
+func NewClip(path string, p palette.Palette, scale int, skip int) (*Clip, error) {
+	c := new(Clip)
+
+	format, ok := CLIP_FORMATS[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, fmt.Errorf("unknown clip format of %q, want a .gif, .png or .apng file", path)
+	}
+
+	if scale <= 0 || skip <= 0 {
+		return nil, fmt.Errorf("clip scale and frame skip must be positive")
+	}
+
+	c.path = path
+	c.format = format
+	c.scale = scale
+	c.skip = skip
+
+	// the shades from the background to the first plane, then the other
+	// planes
+	for i := 0; i < CLIP_SHADES; i++ {
+		c.colors = append(c.colors, Mix(p.Background(), p.Color(1), float64(i)/float64(CLIP_SHADES-1)))
+	}
+	c.colors = append(c.colors, p.Color(2), p.Color(3))
+
+	return c, nil
+}
+
+// Path returns the file the clip is written to.
+func (c *Clip) Path() string {
+	return c.path
+}
+
+// Frames returns the number of frames recorded so far, after skipping.
+func (c *Clip) Frames() int {
+	return len(c.frames)
+}
+
+// Frame records the screen at the end of a frame, through the filter if
+// there is one. It must be called once every 60th of a second of game time.
+func (c *Clip) Frame(fb *display.Framebuffer, f *display.Filter) {
+	c.counted++
+
+	if len(c.frames) > 0 && (c.counted-1)%c.skip != 0 {
+		c.delays[len(c.delays)-1]++
+
+		return
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, fb.Width(), fb.Height()), c.colors)
+
+	for row := 0; row < fb.Height(); row++ {
+		for col := 0; col < fb.Width(); col++ {
+			var shade float64
+			switch {
+			case f != nil:
+				shade = f.Intensity(col, row)
+			case fb.IsPixelSet(col, row):
+				shade = 1
+			}
+
+			img.SetColorIndex(col, row, uint8(shade*float64(CLIP_SHADES-1)+0.5))
+		}
+	}
+
+	if n := len(c.frames); n > 0 && bytes.Equal(c.frames[n-1].Pix, img.Pix) {
+		c.delays[n-1]++
+
+		return
+	}
+
+	c.frames = append(c.frames, img)
+	c.delays = append(c.delays, 1)
+}
+
+// Close writes the clip to its file.
+func (c *Clip) Close() error {
+	if len(c.frames) == 0 {
+		return fmt.Errorf("no frames recorded")
+	}
+
+	f, err := os.Create(c.path)
+	if err != nil {
+		return err
+	}
+
+	if c.format == "gif" {
+		err = c.writeGIF(f)
+	} else {
+		err = c.writeAPNG(f)
+	}
+
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// scaled returns a frame scaled up to the size of the clip.
+func (c *Clip) scaled(img *image.Paletted) *image.Paletted {
+	if c.scale == 1 {
+		return img
+	}
+
+	b := img.Bounds()
+	out := image.NewPaletted(image.Rect(0, 0, b.Dx()*c.scale, b.Dy()*c.scale), img.Palette)
+
+	for y := 0; y < out.Bounds().Dy(); y++ {
+		for x := 0; x < out.Bounds().Dx(); x++ {
+			out.SetColorIndex(x, y, img.ColorIndexAt(x/c.scale, y/c.scale))
+		}
+	}
+
+	return out
+}
+
+// writeGIF writes the clip as an animated GIF. GIF delays are counted in
+// 100ths of a second, so frames start at their 60 Hz time rounded, which
+// keeps the total in sync. A frame that would last less than MIN_GIF_DELAY
+// is replaced by the one after it.
+func (c *Clip) writeGIF(f *os.File) error {
+	type shown struct {
+		frame *image.Paletted
+		start int // in 100ths of a second
+	}
+
+	var frames []shown
+
+	elapsed := 0 // in 60ths of a second
+	for i, frame := range c.frames {
+		start := (elapsed*100 + 30) / 60
+		elapsed += c.delays[i]
+
+		if n := len(frames); n > 0 && start-frames[n-1].start < MIN_GIF_DELAY {
+			frames[n-1].frame = frame
+
+			continue
+		}
+
+		frames = append(frames, shown{frame: frame, start: start})
+	}
+
+	end := (elapsed*100 + 30) / 60
+
+	// the last frame too, keeping the screen it ends on
+	if n := len(frames); n > 1 && end-frames[n-1].start < MIN_GIF_DELAY {
+		frames[n-2].frame = frames[n-1].frame
+		frames = frames[:n-1]
+	}
+
+	anim := &gif.GIF{}
+
+	for i, s := range frames {
+		next := end
+		if i+1 < len(frames) {
+			next = frames[i+1].start
+		}
+
+		anim.Image = append(anim.Image, c.scaled(s.frame))
+		anim.Delay = append(anim.Delay, max(next-s.start, MIN_GIF_DELAY))
+	}
+
+	return gif.EncodeAll(f, anim)
+}
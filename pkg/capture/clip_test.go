@@ -0,0 +1,178 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otaviohenrique/zamorak/pkg/display"
+	"github.com/otaviohenrique/zamorak/pkg/palette"
+)
+
+// record feeds a clip one frame per pattern, a pattern says whether pixel 0,
+// 0 is lit.
+func record(t *testing.T, path string, skip int, lit ...bool) {
+	t.Helper()
+
+	c, err := NewClip(path, palette.PRESETS[palette.DEFAULT_PRESET], 1, skip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fb := display.NewFramebuffer(8, 4)
+	for _, on := range lit {
+		fb.Set(0, 0, on)
+		c.Frame(fb, nil)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// flicker returns n frames alternating between lit and unlit.
+func flicker(n int) []bool {
+	lit := make([]bool, n)
+	for i := range lit {
+		lit[i] = i%2 == 0
+	}
+
+	return lit
+}
+
+func TestGIFDelays(t *testing.T) {
+	tests := []struct {
+		name   string
+		lit    []bool
+		frames int // at most
+	}{
+		{"one second of flicker", flicker(60), 50},
+		{"odd length", flicker(61), 51},
+		{"still", make([]bool, 90), 1},
+		{"single frame", []bool{true}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "clip.gif")
+			record(t, path, 1, tt.lit...)
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			anim, err := gif.DecodeAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			total := 0
+			for i, d := range anim.Delay {
+				if d < MIN_GIF_DELAY {
+					t.Errorf("frame %d lasts %d/100 s, want at least %d", i, d, MIN_GIF_DELAY)
+				}
+
+				total += d
+			}
+
+			// in sync with 60 Hz, rounded
+			want := max((len(tt.lit)*100+30)/60, MIN_GIF_DELAY)
+			if total != want {
+				t.Errorf("clip lasts %d/100 s, want %d", total, want)
+			}
+
+			if len(anim.Image) > tt.frames {
+				t.Errorf("%d frames, want at most %d", len(anim.Image), tt.frames)
+			}
+		})
+	}
+}
+
+func TestAPNG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clip.png")
+
+	// lit for two frames, unlit for one, lit again
+	record(t, path, 1, true, true, false, true)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// decoders without APNG support show the first frame
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if img.Bounds().Dx() != 8 || img.Bounds().Dy() != 4 {
+		t.Fatalf("image is %v, want 8x4", img.Bounds())
+	}
+
+	p := palette.PRESETS[palette.DEFAULT_PRESET]
+	if got := color.RGBAModel.Convert(img.At(0, 0)); got != p.Color(1) {
+		t.Errorf("pixel 0, 0 = %v, want %v", got, p.Color(1))
+	}
+	if got := color.RGBAModel.Convert(img.At(1, 0)); got != p.Background() {
+		t.Errorf("pixel 1, 0 = %v, want %v", got, p.Background())
+	}
+
+	chunks, err := readChunks(data[len(pngSignature):])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var kinds []string
+	var delays []uint16
+	seq := uint32(0)
+
+	for i, ch := range chunks {
+		kinds = append(kinds, ch.kind)
+
+		// the checksum follows the data
+		end := len(pngSignature)
+		for _, prev := range chunks[:i+1] {
+			end += 12 + len(prev.data)
+		}
+		if crc := crc32.ChecksumIEEE(data[end-8-len(ch.data) : end-4]); crc != binary.BigEndian.Uint32(data[end-4:]) {
+			t.Errorf("chunk %d, %s, has a bad checksum", i, ch.kind)
+		}
+
+		switch ch.kind {
+		case "acTL":
+			if n := binary.BigEndian.Uint32(ch.data); n != 3 {
+				t.Errorf("acTL says %d frames, want 3", n)
+			}
+		case "fcTL", "fdAT":
+			if n := binary.BigEndian.Uint32(ch.data); n != seq {
+				t.Errorf("%s has sequence number %d, want %d", ch.kind, n, seq)
+			}
+			seq++
+
+			if ch.kind == "fcTL" {
+				delays = append(delays, binary.BigEndian.Uint16(ch.data[20:]))
+
+				if den := binary.BigEndian.Uint16(ch.data[22:]); den != 60 {
+					t.Errorf("delay denominator = %d, want 60", den)
+				}
+			}
+		}
+	}
+
+	if kinds[0] != "IHDR" || kinds[1] != "acTL" || kinds[len(kinds)-1] != "IEND" {
+		t.Errorf("chunks = %v, want IHDR, acTL first and IEND last", kinds)
+	}
+
+	if want := []uint16{2, 1, 1}; fmt.Sprint(delays) != fmt.Sprint(want) {
+		t.Errorf("delays = %v, want %v", delays, want)
+	}
+}
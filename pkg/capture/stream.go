@@ -0,0 +1,105 @@
+package capture
+
+import (
+	"errors"
+	"image"
+	"os"
+
+	"github.com/otaviohenrique/zamorak/pkg/beeper"
+)
+
+// Streams writes the video and the sound of a game as raw streams, for an
+// external encoder to turn into a video file. Both are fed once per emulated
+// frame, so they stay in sync with the game whatever the host does: paused
+// games add nothing and fast forwarded ones add frames faster.
+type Streams struct {
+	video     *Y4MWriter
+	videoFile *os.File
+	audio     *WAVWriter
+	audioFile *os.File
+	beeper    *beeper.Beeper
+}
+
+// OpenStreams opens the video stream at videoPath and the sound stream at
+// audioPath, either can be empty to leave it out. Paths may be files or
+// named pipes, opening a pipe waits for its reader.
+func OpenStreams(videoPath string, audioPath string, width, height, scale int) (*Streams, error) {
+	s := new(Streams)
+
+	if videoPath != "" {
+		f, err := os.OpenFile(videoPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+		if err != nil {
+			return nil, err
+		}
+
+		s.videoFile = f
+
+		s.video, err = NewY4MWriter(f, width, height, scale)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+
+	if audioPath != "" {
+		f, err := os.OpenFile(audioPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+
+		s.audioFile = f
+		s.beeper = beeper.New(beeper.DEFAULT_SAMPLE_RATE)
+
+		s.audio, err = NewWAVWriter(f, s.beeper.Rate())
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Beeper returns the synthesizer of the sound stream, to set its pitch and
+// volume, or nil when there is no sound stream.
+func (s *Streams) Beeper() *beeper.Beeper {
+	return s.beeper
+}
+
+// Frame writes the screen at the end of a frame and the sound of that frame,
+// a beep when the sound timer was running.
+func (s *Streams) Frame(img *image.RGBA, beeping bool) error {
+	if s.video != nil {
+		if err := s.video.Frame(img); err != nil {
+			return err
+		}
+	}
+
+	if s.audio != nil {
+		if err := s.audio.Write(s.beeper.Frame(beeping)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close ends both streams, later calls do nothing.
+func (s *Streams) Close() error {
+	var errs []error
+
+	if s.audio != nil {
+		errs = append(errs, s.audio.Close())
+	}
+
+	for _, f := range []*os.File{s.videoFile, s.audioFile} {
+		if f != nil {
+			errs = append(errs, f.Close())
+		}
+	}
+
+	*s = Streams{}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,93 @@
+package capture
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// size written in the header while the length of the stream is unknown,
+// what readers of piped WAV expect
+const wavUnknownSize = 0xFFFFFFFF
+
+// WAVWriter writes 16 bit mono PCM as a WAV stream. The sizes in the header
+// are only known once the stream ends, so they are patched on Close when the
+// destination can seek, and left as unknown otherwise, ex: on a pipe.
+type WAVWriter struct {
+	w    io.Writer
+	size uint32 // bytes of samples written
+}
+
+func NewWAVWriter(w io.Writer, rate int) (*WAVWriter, error) {
+	wav := new(WAVWriter)
+
+	wav.w = w
+
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], wavUnknownSize)
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(header[22:], 1) // mono
+	binary.LittleEndian.PutUint32(header[24:], uint32(rate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(rate*2))
+	binary.LittleEndian.PutUint16(header[32:], 2)
+	binary.LittleEndian.PutUint16(header[34:], 16)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], wavUnknownSize)
+
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return wav, nil
+}
+
+// Write appends samples to the stream.
+func (wav *WAVWriter) Write(samples []int16) error {
+	buf := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(buf[2*i:], uint16(s))
+	}
+
+	wav.size += uint32(len(buf))
+
+	_, err := wav.w.Write(buf)
+
+	return err
+}
+
+// Close writes the sizes into the header when the destination can seek. It
+// does not close the destination.
+func (wav *WAVWriter) Close() error {
+	s, ok := wav.w.(io.WriteSeeker)
+	if !ok {
+		return nil
+	}
+
+	// pipes are files too, but cannot seek
+	if _, err := s.Seek(4, io.SeekStart); err != nil {
+		return nil
+	}
+
+	var size [4]byte
+
+	binary.LittleEndian.PutUint32(size[:], 36+wav.size)
+	if _, err := s.Write(size[:]); err != nil {
+		return err
+	}
+
+	if _, err := s.Seek(40, io.SeekStart); err != nil {
+		return err
+	}
+
+	binary.LittleEndian.PutUint32(size[:], wav.size)
+	if _, err := s.Write(size[:]); err != nil {
+		return err
+	}
+
+	_, err := s.Seek(0, io.SeekEnd)
+
+	return err
+}
@@ -0,0 +1,119 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otaviohenrique/zamorak/pkg/display"
+	"github.com/otaviohenrique/zamorak/pkg/palette"
+)
+
+func TestWAVSizesAfterClose(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	wav, err := NewWAVWriter(f, 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wav.Write([]int16{1, -1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := wav.Write([]int16{-2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := wav.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) != 44+8 {
+		t.Fatalf("file is %d bytes, want %d", len(data), 44+8)
+	}
+
+	if got := binary.LittleEndian.Uint32(data[4:]); got != 36+8 {
+		t.Errorf("RIFF size = %d, want %d", got, 36+8)
+	}
+
+	if got := binary.LittleEndian.Uint32(data[40:]); got != 8 {
+		t.Errorf("data size = %d, want 8", got)
+	}
+
+	if got := binary.LittleEndian.Uint32(data[24:]); got != 48000 {
+		t.Errorf("sample rate = %d, want 48000", got)
+	}
+
+	if want := []byte{1, 0, 0xFF, 0xFF, 2, 0, 0xFE, 0xFF}; !bytes.Equal(data[44:], want) {
+		t.Errorf("samples = % x, want % x", data[44:], want)
+	}
+
+	// Close leaves the file at its end for later writes
+	if pos, _ := f.Seek(0, io.SeekCurrent); pos != int64(len(data)) {
+		t.Errorf("file left at %d, want %d", pos, len(data))
+	}
+}
+
+func TestWAVUnknownSizesWithoutSeeking(t *testing.T) {
+	var out bytes.Buffer
+
+	wav, err := NewWAVWriter(&out, 48000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wav.Write([]int16{1, 2})
+
+	if err := wav.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := out.Bytes()
+
+	if got := binary.LittleEndian.Uint32(data[4:]); got != wavUnknownSize {
+		t.Errorf("RIFF size = %#x, want %#x", got, wavUnknownSize)
+	}
+
+	if got := binary.LittleEndian.Uint32(data[40:]); got != wavUnknownSize {
+		t.Errorf("data size = %#x, want %#x", got, wavUnknownSize)
+	}
+}
+
+func TestY4M(t *testing.T) {
+	var out bytes.Buffer
+
+	y, err := NewY4MWriter(&out, 2, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fb := display.NewFramebuffer(2, 1)
+	fb.Set(0, 0, true)
+
+	if err := y.Frame(Render(fb, palette.PRESETS["classic"], nil, 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	header := "YUV4MPEG2 W4 H2 F60:1 Ip A1:1 C444 XCOLORRANGE=FULL\nFRAME\n"
+	if got := out.String(); len(got) != len(header)+3*4*2 || got[:len(header)] != header {
+		t.Fatalf("stream = %q, want %q and 24 bytes of planes", got, header)
+	}
+
+	// white then black luma, twice as wide and high
+	lum := out.Bytes()[len(header) : len(header)+8]
+	if want := []byte{255, 255, 0, 0, 255, 255, 0, 0}; !bytes.Equal(lum, want) {
+		t.Errorf("luma = %v, want %v", lum, want)
+	}
+}
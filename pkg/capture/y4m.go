@@ -0,0 +1,66 @@
+package capture
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// Y4MWriter writes frames as a YUV4MPEG2 stream at 60 frames per second, in
+// full range 4:4:4 so the colors of the palette go through unchanged. Ex, to
+// encode it losslessly: ffmpeg -i out.y4m -c:v libx264rgb -crf 0 out.mp4.
+type Y4MWriter struct {
+	w      *bufio.Writer
+	width  int
+	height int
+	scale  int
+	planes []byte
+}
+
+// NewY4MWriter writes frames of width x height, every scale x scale block of
+// the stream being a pixel of the frames.
+func NewY4MWriter(w io.Writer, width, height, scale int) (*Y4MWriter, error) {
+	y := new(Y4MWriter)
+
+	y.w = bufio.NewWriter(w)
+	y.width = width * scale
+	y.height = height * scale
+	y.scale = scale
+	y.planes = make([]byte, 3*y.width*y.height)
+
+	if _, err := fmt.Fprintf(y.w, "YUV4MPEG2 W%d H%d F60:1 Ip A1:1 C444 XCOLORRANGE=FULL\n", y.width, y.height); err != nil {
+		return nil, err
+	}
+
+	return y, nil
+}
+
+// Frame writes a frame.
+func (y *Y4MWriter) Frame(img *image.RGBA) error {
+	n := y.width * y.height
+	b := img.Bounds()
+
+	for row := 0; row < y.height; row++ {
+		for col := 0; col < y.width; col++ {
+			c := img.RGBAAt(b.Min.X+col/y.scale, b.Min.Y+row/y.scale)
+			lum, cb, cr := color.RGBToYCbCr(c.R, c.G, c.B)
+
+			i := row*y.width + col
+			y.planes[i] = lum
+			y.planes[n+i] = cb
+			y.planes[2*n+i] = cr
+		}
+	}
+
+	if _, err := y.w.WriteString("FRAME\n"); err != nil {
+		return err
+	}
+
+	if _, err := y.w.Write(y.planes); err != nil {
+		return err
+	}
+
+	return y.w.Flush()
+}
@@ -0,0 +1,168 @@
+// Package chip8test runs CHIP-8 programs inside go test, without a window or
+// audio device, and compares their screens with golden files:
+//
+//	func TestTitleScreen(t *testing.T) {
+//		h := chip8test.Load(t, "testdata/game.ch8", "chip8")
+//		h.Tap(0x5, 10, 3)
+//		h.RunFrames(120)
+//		h.AssertScreen("testdata/title.txt")
+//	}
+//
+// Run go test -update to write the golden files from the current screens,
+// after registering the flag from TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		chip8test.RegisterUpdateFlag()
+//		os.Exit(m.Run())
+//	}
+//
+// ZAMORAK_UPDATE_GOLDEN=1 go test does the same without the flag.
+package chip8test
+
+import (
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otaviohenrique/zamorak/pkg/decoder"
+	"github.com/otaviohenrique/zamorak/pkg/display"
+	"github.com/otaviohenrique/zamorak/pkg/headless"
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+)
+
+var (
+	// Seed used by Load and New, so runs are repeatable.
+	SEED int64 = 1
+
+	// AssertScreen writes the golden files instead of comparing against them
+	// when set, when the UPDATE_FLAG flag is true or when UPDATE_ENV is set
+	// to anything but empty
+	UPDATE = false
+
+	UPDATE_FLAG = "update"
+	UPDATE_ENV  = "ZAMORAK_UPDATE_GOLDEN"
+)
+
+// RegisterUpdateFlag defines the UPDATE_FLAG flag of the test binary, unless
+// the test already did. It must be called before the flags are parsed, from
+// TestMain or an init function of the test.
+func RegisterUpdateFlag() {
+	if flag.Lookup(UPDATE_FLAG) == nil {
+		flag.Bool(UPDATE_FLAG, false, "write golden files instead of comparing against them")
+	}
+}
+
+func updating() bool {
+	if f := flag.Lookup(UPDATE_FLAG); f != nil && f.Value.String() == "true" {
+		return true
+	}
+
+	return UPDATE || os.Getenv(UPDATE_ENV) != ""
+}
+
+// Harness drives one headless machine from a test.
+type Harness struct {
+	t       testing.TB
+	machine *headless.Machine
+}
+
+// Load reads a ROM from disk and starts it with the quirks of the named
+// profile (chip8, schip, xochip or megachip).
+func Load(t testing.TB, path string, profile string) *Harness {
+	t.Helper()
+
+	programData, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("chip8test: %v", err)
+	}
+
+	platform, err := decoder.ParsePlatform(profile)
+	if err != nil {
+		t.Fatalf("chip8test: %v", err)
+	}
+
+	return New(t, programData, interpreter.QuirksForProfile(platform))
+}
+
+// New starts a program already in memory with the given quirks.
+func New(t testing.TB, programData []byte, quirks interpreter.Quirks) *Harness {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	m, err := headless.NewMachine(log, quirks, SEED, programData)
+	if err != nil {
+		t.Fatalf("chip8test: %v", err)
+	}
+
+	return &Harness{t: t, machine: m}
+}
+
+// Machine gives access to the interpreter and framebuffer for custom checks.
+func (h *Harness) Machine() *headless.Machine {
+	return h.machine
+}
+
+// Press holds key down from the start of the given frame.
+func (h *Harness) Press(key byte, frame int) {
+	h.machine.Schedule(headless.Event{Frame: frame, Key: key, Pressed: true})
+}
+
+// Release lets go of key at the start of the given frame.
+func (h *Harness) Release(key byte, frame int) {
+	h.machine.Schedule(headless.Event{Frame: frame, Key: key, Pressed: false})
+}
+
+// Tap holds key down for the given number of frames.
+func (h *Harness) Tap(key byte, frame int, frames int) {
+	h.Press(key, frame)
+	h.Release(key, frame+frames)
+}
+
+// RunFrames runs n frames, failing the test if the program crashes the
+// interpreter.
+func (h *Harness) RunFrames(n int) {
+	h.t.Helper()
+
+	if err := h.machine.RunFrames(n); err != nil {
+		h.t.Fatalf("chip8test: %v", err)
+	}
+}
+
+// AssertScreen compares the screen with a golden file: text with one line per
+// row, # for lit pixels and . for the others, when the name ends in .txt, or
+// a PNG image otherwise. When updating the file is written instead.
+func (h *Harness) AssertScreen(golden string) {
+	h.t.Helper()
+
+	screen := h.machine.Runtime().Framebuffer()
+
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+			h.t.Fatalf("chip8test: %v", err)
+		}
+
+		if err := os.WriteFile(golden, screen.EncodeGolden(golden), 0o644); err != nil {
+			h.t.Fatalf("chip8test: %v", err)
+		}
+
+		return
+	}
+
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		h.t.Fatalf("chip8test: %v (run with %s=1 to create it)", err, UPDATE_ENV)
+	}
+
+	want, err := display.ReadGolden(golden, data)
+	if err != nil {
+		h.t.Fatalf("chip8test: %s: %v", golden, err)
+	}
+
+	if diff := display.Diff(want, screen); diff != "" {
+		h.t.Errorf("chip8test: screen at frame %d does not match %s:\n%s", h.machine.Frame(), golden, diff)
+	}
+}
@@ -0,0 +1,68 @@
+package chip8test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+)
+
+// draws the font digit held in V0 at the top left corner, V0 is 0 until key
+// 5 is pressed and 5 after it
+var digitProgram = []byte{
+	0x65, 0x05, // 6505: V5 = 5, the key checked
+	0x00, 0xE0, // 00E0: clear
+	0xF0, 0x29, // F029: I = digit V0
+	0xD1, 0x15, // D115: draw at V1, V1
+	0xE5, 0x9E, // E59E: skip the jump when the key in V5 is down
+	0x12, 0x02, // 1202: draw again
+	0x60, 0x05, // 6005: V0 = 5
+	0x12, 0x02, // 1202: draw again
+}
+
+func TestMain(m *testing.M) {
+	RegisterUpdateFlag()
+	os.Exit(m.Run())
+}
+
+func TestAssertScreenMatchesGolden(t *testing.T) {
+	h := New(t, digitProgram, interpreter.Quirks{})
+	h.RunFrames(2)
+	h.AssertScreen("testdata/zero.txt")
+
+	h.Tap(0x5, 2, 1)
+	h.RunFrames(2)
+	h.AssertScreen("testdata/five.txt")
+}
+
+func TestAssertScreenUpdatesGolden(t *testing.T) {
+	UPDATE = true
+	defer func() { UPDATE = false }()
+
+	golden := filepath.Join(t.TempDir(), "screen.png")
+
+	h := New(t, digitProgram, interpreter.Quirks{})
+	h.RunFrames(2)
+	h.AssertScreen(golden)
+
+	// read back instead of written
+	UPDATE = false
+	h.AssertScreen(golden)
+}
+
+func TestUpdateFlag(t *testing.T) {
+	if updating() {
+		t.Skip("golden files are being updated")
+	}
+
+	if err := flag.Set(UPDATE_FLAG, "true"); err != nil {
+		t.Fatal(err)
+	}
+	defer flag.Set(UPDATE_FLAG, "false")
+
+	if !updating() {
+		t.Errorf("-%s does not update golden files", UPDATE_FLAG)
+	}
+}
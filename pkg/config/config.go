@@ -0,0 +1,233 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// file read from the home directory when --config is not given
+	FILE_NAME = ".zamorak.yaml"
+
+	// environment variable holding the path of the config file
+	FILE_ENV = "ZAMORAK_CONFIG"
+
+	// prefix of the environment variables overriding settings, ex:
+	// ZAMORAK_AUDIO_VOLUME for audio.volume
+	ENV_PREFIX = "ZAMORAK_"
+
+	// section of the file holding the per ROM overrides
+	ROMS_SECTION = "roms"
+
+	// every setting the file can hold
+	KEYS = []Key{
+		{Name: "profile", Flag: "profile", Usage: "platform the ROM targets: chip8, schip, xochip or megachip"},
+		{Name: "quirks", Flag: "quirks", Usage: "quirks to override on top of the profile, ex: shifting,memory=off"},
+		{Name: "speed", Flag: "speed", Usage: "emulation speed multiplier"},
+		{Name: "ipf", Flag: "ipf", Usage: "instructions executed per frame"},
+		{Name: "fast-forward", Flag: "fast-forward-speed", Usage: "speed multiplier while fast forwarding"},
+		{Name: "palette", Flag: "palette", Usage: "colors the screen is drawn with"},
+		{Name: "display.filter", Flag: "filter", Usage: "anti-flicker filter: none, decay, blend or vblank"},
+		{Name: "display.filter-strength", Flag: "filter-strength", Usage: "strength of the anti-flicker filter, from 0 to 1"},
+		{Name: "display.shaders", Flag: "shaders", Usage: "post-processing shaders, ex: crt:curvature=0.2,scanlines"},
+		{Name: "keymap", Flag: "keymap", Usage: "keyboard layout the CHIP-8 keypad is mapped to"},
+		{Name: "audio.volume", Flag: "volume", Usage: "beeper volume, from 0 to 1"},
+		{Name: "audio.mute", Flag: "mute", Usage: "start with the beeper muted"},
+		{Name: "audio.frequency", Flag: "beep-frequency", Usage: "pitch of the beep in exported and streamed sound, in Hz"},
+		{Name: "frontend", Flag: "frontend", Usage: "where the game is shown: window, tty, sixel or kitty"},
+		{Name: "tty.renderer", Flag: "tty-renderer", Usage: "how the terminal frontend draws pixels: halfblock or braille"},
+		{Name: "tty.color", Flag: "tty-color", Usage: "draw in the colors of the palette in the terminal"},
+		{Name: "tty.key-timeout", Flag: "tty-key-timeout", Usage: "release a key in the terminal when it was not repeated for this long"},
+		{Name: "window.scale", Flag: "scale", Usage: "game size as a multiple of the screen resolution, in whole pixels"},
+		{Name: "window.fullscreen", Flag: "fullscreen", Usage: "start in full screen"},
+		{Name: "window.borderless", Flag: "borderless", Usage: "open the window without a title bar and borders"},
+		{Name: "window.resizable", Flag: "resizable", Usage: "let the window be resized"},
+		{Name: "window.rotation", Flag: "rotation", Usage: "screen rotation: 0, 90, 180 or 270 degrees"},
+		{Name: "touch.keypad", Flag: "touch-keypad", Usage: "on-screen keypad: off, side, below or over"},
+		{Name: "touch.layout", Flag: "touch-layout", Usage: "key order of the on-screen keypad: vip or hex"},
+		{Name: "touch.opacity", Flag: "touch-opacity", Usage: "opacity of the on-screen keypad, from 0 to 1"},
+		{Name: "hotkeys", Flag: "hotkeys", Usage: "keys bound to emulator actions, ex: pause=P,reset=F5", Map: true},
+		{Name: "window.osd", Flag: "osd", Usage: "show the on-screen display of speed and machine state"},
+		{Name: "window.pause-on-blur", Flag: "pause-on-blur", Usage: "pause while the window does not have focus"},
+		{Name: "capture.dir", Flag: "capture-dir", Usage: "directory screenshots and clips are saved to"},
+		{Name: "capture.screenshot-scale", Flag: "screenshot-scale", Usage: "screenshot size as a multiple of the screen resolution"},
+		{Name: "capture.record-format", Flag: "record-format", Usage: "format of clips recorded with the hotkey: gif or apng"},
+		{Name: "capture.record-scale", Flag: "record-scale", Usage: "clip size as a multiple of the screen resolution"},
+		{Name: "capture.record-skip", Flag: "record-skip", Usage: "keep one frame out of this many in clips"},
+		{Name: "capture.video-scale", Flag: "video-scale", Usage: "video stream size as a multiple of the screen resolution"},
+		{Name: "log.level", Flag: "log-level", Usage: "log level"},
+		{Name: "log.levels", Flag: "log-levels", Usage: "log levels per subsystem, ex: cpu=debug", Map: true},
+		{Name: "log.format", Flag: "log-format", Usage: "log format: text or json"},
+		{Name: "log.file", Flag: "log-file", Usage: "file logs are written to"},
+		{Name: "recorder.size", Flag: "flight-recorder", Usage: "instructions kept by the flight recorder"},
+		{Name: "recorder.dir", Flag: "dump-dir", Usage: "directory flight recorder dumps are written to"},
+	}
+)
+
+// Key is a setting of the config file and the command line flag it sets.
+type Key struct {
+	Name  string // dotted path in the file, ex: audio.volume
+	Flag  string
+	Usage string
+	Map   bool // a YAML mapping, passed to the flag as k=v,k=v
+}
+
+// Env returns the environment variable overriding the setting.
+func (k Key) Env() string {
+	name := strings.NewReplacer(".", "_", "-", "_").Replace(k.Name)
+
+	return ENV_PREFIX + strings.ToUpper(name)
+}
+
+// Lookup finds a key by name.
+func Lookup(name string) (Key, bool) {
+	for _, k := range KEYS {
+		if k.Name == name {
+			return k, true
+		}
+	}
+
+	return Key{}, false
+}
+
+// Path returns the config file to use: the one named by ZAMORAK_CONFIG, or
+// ~/.zamorak.yaml.
+func Path() (string, error) {
+	if path := os.Getenv(FILE_ENV); path != "" {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, FILE_NAME), nil
+}
+
+// Source says which layer a setting comes from.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceROM     Source = "rom"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Setting is the value a key resolved to.
+type Setting struct {
+	Key    Key
+	Value  string
+	Source Source
+	Origin string // file section or environment variable the value came from
+}
+
+// Resolve layers the global settings, the sections matching a ROM and the
+// environment, later layers winning. Sections are matched by file name first
+// and then by hash, so a hash section wins over a name section. romPath may
+// be empty to resolve the global settings only. Keys that are not set
+// anywhere are left out.
+func (f *File) Resolve(romPath string, programData []byte, environ []string) map[string]Setting {
+	settings := make(map[string]Setting)
+
+	set := func(values map[string]string, source Source, origin string) {
+		for name, value := range values {
+			if k, ok := Lookup(name); ok {
+				settings[name] = Setting{Key: k, Value: value, Source: source, Origin: origin}
+			}
+		}
+	}
+
+	set(f.Global, SourceFile, f.path)
+
+	if romPath != "" {
+		for _, section := range f.Sections(romPath, programData) {
+			set(f.ROMs[section], SourceROM, ROMS_SECTION+"."+section)
+		}
+	}
+
+	env := make(map[string]string)
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			env[name] = value
+		}
+	}
+
+	for _, k := range KEYS {
+		if value, ok := env[k.Env()]; ok {
+			settings[k.Name] = Setting{Key: k, Value: value, Source: SourceEnv, Origin: k.Env()}
+		}
+	}
+
+	return settings
+}
+
+// Sorted returns settings in the order of KEYS.
+func Sorted(settings map[string]Setting) []Setting {
+	sorted := make([]Setting, 0, len(settings))
+	for _, s := range settings {
+		sorted = append(sorted, s)
+	}
+
+	order := make(map[string]int)
+	for i, k := range KEYS {
+		order[k.Name] = i
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return order[sorted[i].Key.Name] < order[sorted[j].Key.Name]
+	})
+
+	return sorted
+}
+
+// validate checks a value set by hand, values read from files are checked
+// when they are applied to flags.
+func validate(k Key, value string) error {
+	if !k.Map || value == "" {
+		return nil
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		if _, _, ok := strings.Cut(part, "="); !ok {
+			return fmt.Errorf("invalid %s %q, want name=value items separated by commas", k.Name, value)
+		}
+	}
+
+	return nil
+}
+
+// ValidateValue checks that a value parses as the type of the flag it is
+// given to, a pflag type name such as bool, int, float64 or duration. Other
+// types are checked by the flag itself when the value is applied.
+func ValidateValue(k Key, flagType string, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	var err error
+
+	switch flagType {
+	case "bool":
+		_, err = strconv.ParseBool(value)
+	case "int", "int64":
+		_, err = strconv.ParseInt(value, 10, 64)
+	case "float64":
+		_, err = strconv.ParseFloat(value, 64)
+	case "duration":
+		_, err = time.ParseDuration(value)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid %s %q, want a %s", k.Name, value, flagType)
+	}
+
+	return nil
+}
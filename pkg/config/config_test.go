@@ -0,0 +1,121 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otaviohenrique/zamorak/pkg/detect"
+)
+
+func TestValidateValue(t *testing.T) {
+	k := Key{Name: "test"}
+
+	tests := []struct {
+		flagType string
+		value    string
+		ok       bool
+	}{
+		{"int", "12", true},
+		{"int", "abc", false},
+		{"int", "1.5", false},
+		{"int64", "-7", true},
+		{"float64", "0.5", true},
+		{"float64", "loud", false},
+		{"bool", "true", true},
+		{"bool", "yes", false},
+		{"duration", "200ms", true},
+		{"duration", "200", false},
+		{"string", "anything", true},
+		{"int", "", true}, // removes the setting
+	}
+
+	for _, tt := range tests {
+		err := ValidateValue(k, tt.flagType, tt.value)
+
+		if (err == nil) != tt.ok {
+			t.Errorf("ValidateValue(%s, %q) = %v, want ok %v", tt.flagType, tt.value, err, tt.ok)
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	f, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Set("", "nope", "1"); err == nil {
+		t.Error("unknown setting accepted")
+	}
+
+	if err := f.Set("", "hotkeys", "pause"); err == nil {
+		t.Error("map setting without name=value accepted")
+	}
+
+	if err := f.Set("Pong.ch8", "keymap", "hex"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := f.ROMs["pong.ch8"]["keymap"]; got != "hex" {
+		t.Errorf("ROM section keymap = %q, want hex", got)
+	}
+
+	if err := f.Set("pong.ch8", "keymap", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := f.ROMs["pong.ch8"]; ok {
+		t.Error("empty ROM section kept after removing its last setting")
+	}
+}
+
+func TestResolveLayers(t *testing.T) {
+	program := []byte{0x12, 0x00}
+
+	path := filepath.Join(t.TempDir(), "zamorak.yaml")
+	data := `
+speed: 2
+ipf: 10
+audio:
+  volume: 0.5
+roms:
+  pong.ch8:
+    speed: 3
+    palette: amber
+  ` + detect.Hash(program) + `:
+    palette: green
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	settings := f.Resolve("roms/pong.ch8", program, []string{"ZAMORAK_IPF=20", "OTHER=1"})
+
+	want := map[string]struct {
+		value  string
+		source Source
+	}{
+		"speed":        {"3", SourceROM},
+		"ipf":          {"20", SourceEnv},
+		"audio.volume": {"0.5", SourceFile},
+		"palette":      {"green", SourceROM}, // the hash section wins
+	}
+
+	if len(settings) != len(want) {
+		t.Errorf("resolved %d settings, want %d: %v", len(settings), len(want), settings)
+	}
+
+	for name, w := range want {
+		s := settings[name]
+
+		if s.Value != w.value || s.Source != w.source {
+			t.Errorf("%s = %q from %s, want %q from %s", name, s.Value, s.Source, w.value, w.source)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/otaviohenrique/zamorak/pkg/detect"
+	"gopkg.in/yaml.v3"
+)
+
+// File is a config file:
+//
+//	profile: chip8
+//	audio:
+//	  volume: 0.5
+//	hotkeys:
+//	  pause: P
+//	roms:
+//	  pong.ch8:
+//	    keymap: qwerty
+//	  0df2789f1dcb2a6b72e2e4d5e0b4f7a1e2ea1ac3:
+//	    profile: schip
+//	    quirks: shifting
+//
+// Settings are kept flattened to their dotted names, ex: audio.volume. The
+// roms section holds overrides keyed by ROM file name or SHA-1, and is where
+// per-ROM profiles, quirks and keymaps go, in place of a bundled ROM
+// compatibility database.
+type File struct {
+	Global map[string]string
+	ROMs   map[string]map[string]string
+	path   string
+}
+
+// Load reads a config file. A missing file is an empty config.
+func Load(path string) (*File, error) {
+	f := &File{Global: make(map[string]string), ROMs: make(map[string]map[string]string), path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var root map[string]any
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for name, value := range root {
+		if name != ROMS_SECTION {
+			if err := flatten(f.Global, name, value); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+
+			continue
+		}
+
+		roms, ok := value.(map[string]any)
+		if !ok && value != nil {
+			return nil, fmt.Errorf("%s: %s must map ROM names or hashes to settings", path, ROMS_SECTION)
+		}
+
+		for section, settings := range roms {
+			values := make(map[string]string)
+
+			if settings != nil {
+				m, ok := settings.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("%s: %s.%s must hold settings", path, ROMS_SECTION, section)
+				}
+
+				for name, value := range m {
+					if err := flatten(values, name, value); err != nil {
+						return nil, fmt.Errorf("%s: %s.%s: %w", path, ROMS_SECTION, section, err)
+					}
+				}
+			}
+
+			f.ROMs[strings.ToLower(section)] = values
+		}
+	}
+
+	return f, nil
+}
+
+// Path returns the file the config was loaded from.
+func (f *File) Path() string {
+	return f.path
+}
+
+// Set changes a setting, in the global section when rom is empty and in the
+// section of that ROM name or hash otherwise. An empty value removes it.
+func (f *File) Set(rom string, name string, value string) error {
+	k, ok := Lookup(name)
+	if !ok {
+		return fmt.Errorf("unknown setting %q", name)
+	}
+
+	if err := validate(k, value); err != nil {
+		return err
+	}
+
+	values := f.Global
+	if rom != "" {
+		rom = strings.ToLower(rom)
+
+		if f.ROMs[rom] == nil {
+			f.ROMs[rom] = make(map[string]string)
+		}
+
+		values = f.ROMs[rom]
+	}
+
+	if value == "" {
+		delete(values, name)
+
+		if rom != "" && len(values) == 0 {
+			delete(f.ROMs, rom)
+		}
+
+		return nil
+	}
+
+	values[name] = value
+
+	return nil
+}
+
+// Sections returns the names of the roms sections that apply to a program,
+// its file name first and then its hash.
+func (f *File) Sections(romPath string, programData []byte) []string {
+	var sections []string
+
+	for _, name := range []string{strings.ToLower(filepath.Base(romPath)), detect.Hash(programData)} {
+		if _, ok := f.ROMs[name]; ok {
+			sections = append(sections, name)
+		}
+	}
+
+	return sections
+}
+
+// Save writes the config back to the file it was loaded from. Comments in
+// the file are not kept.
+func (f *File) Save() error {
+	root := nest(f.Global)
+
+	if len(f.ROMs) > 0 {
+		roms := make(map[string]any)
+		for section, values := range f.ROMs {
+			roms[section] = nest(values)
+		}
+
+		root[ROMS_SECTION] = roms
+	}
+
+	var buf bytes.Buffer
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(f.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(f.path, buf.Bytes(), 0o644)
+}
+
+// flatten stores a YAML value under its dotted name.
+func flatten(values map[string]string, name string, value any) error {
+	k, known := Lookup(name)
+
+	// mappings with keys that are not strings, ex: keypad digits
+	if m, ok := value.(map[any]any); ok {
+		converted := make(map[string]any, len(m))
+		for key, setting := range m {
+			converted[fmt.Sprint(key)] = setting
+		}
+
+		value = converted
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		if known && k.Map {
+			items := make([]string, 0, len(v))
+			for item, setting := range v {
+				items = append(items, fmt.Sprintf("%s=%v", item, setting))
+			}
+			sort.Strings(items)
+
+			values[name] = strings.Join(items, ",")
+
+			return nil
+		}
+
+		for child, setting := range v {
+			if err := flatten(values, name+"."+child, setting); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case []any:
+		return fmt.Errorf("%s: lists are not supported", name)
+	}
+
+	if !known {
+		return fmt.Errorf("unknown setting %q", name)
+	}
+
+	if k.Map {
+		return fmt.Errorf("%s must be a mapping", name)
+	}
+
+	values[name] = fmt.Sprint(value)
+
+	return nil
+}
+
+// nest turns dotted names back into nested mappings.
+func nest(values map[string]string) map[string]any {
+	root := make(map[string]any)
+
+	for name, value := range values {
+		parts := strings.Split(name, ".")
+
+		m := root
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := m[part].(map[string]any)
+			if !ok {
+				child = make(map[string]any)
+				m[part] = child
+			}
+
+			m = child
+		}
+
+		m[parts[len(parts)-1]] = typed(name, value)
+	}
+
+	return root
+}
+
+// typed returns a value as the YAML type it looks like, so numbers and
+// booleans are not written quoted.
+func typed(name string, value string) any {
+	if k, ok := Lookup(name); ok && k.Map {
+		items := make(map[string]any)
+
+		for _, part := range strings.Split(value, ",") {
+			item, setting, _ := strings.Cut(part, "=")
+			items[strings.TrimSpace(item)] = strings.TrimSpace(setting)
+		}
+
+		return items
+	}
+
+	if value == "true" || value == "false" {
+		return value == "true"
+	}
+
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+
+	return value
+}
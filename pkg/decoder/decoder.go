@@ -0,0 +1,124 @@
+package decoder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Platform identifies the CHIP-8 family member an instruction belongs to.
+// Platforms are ordered so that a later one is a superset of an earlier one,
+// except for MEGA-CHIP, which extends SUPER-CHIP on its own branch.
+type Platform int
+
+const (
+	PlatformCHIP8 Platform = iota
+	PlatformSCHIP
+	PlatformXOCHIP
+	PlatformMEGACHIP
+)
+
+var platformNames = map[Platform]string{
+	PlatformCHIP8:    "chip8",
+	PlatformSCHIP:    "schip",
+	PlatformXOCHIP:   "xochip",
+	PlatformMEGACHIP: "megachip",
+}
+
+func (p Platform) String() string {
+	if name, ok := platformNames[p]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("platform(%d)", int(p))
+}
+
+// ParsePlatform returns the platform with the given name, as used by the
+// --profile flag (chip8, schip, xochip or megachip).
+func ParsePlatform(name string) (Platform, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	for p, n := range platformNames {
+		if n == name {
+			return p, nil
+		}
+	}
+
+	return PlatformCHIP8, fmt.Errorf("unknown platform %q", name)
+}
+
+// Instruction is a 2 byte opcode split into the nibbles and bytes the
+// interpreter dispatches on.
+type Instruction struct {
+	Opcode uint16
+	Kind   byte   // first nibble, the instruction
+	X      byte   // second nibble, register lookup!
+	Y      byte   // third nibble, register lookup!
+	N      byte   // fourth nibble, 4 bit number
+	NN     byte   // second byte (KK)
+	NNN    uint16 // second, third and fourth nibbles
+}
+
+// Decode splits the opcode formed by b0 and b1.
+func Decode(b0, b1 byte) Instruction {
+	X := b0 & 0x0F
+	NN := b1
+
+	return Instruction{
+		Opcode: uint16(b0)<<8 | uint16(b1),
+		Kind:   (b0 & 0xF0) >> 4,
+		X:      X,
+		Y:      (b1 & 0xF0) >> 4,
+		N:      b1 & 0x0F,
+		NN:     NN,
+		NNN:    uint16(X)<<8 | uint16(NN),
+	}
+}
+
+// Size returns how many bytes the instruction takes in memory. Every
+// instruction is 2 bytes long except XO-CHIP's F000 NNNN.
+func (i Instruction) Size() uint16 {
+	if i.Opcode == 0xF000 {
+		return 4
+	}
+
+	return 2
+}
+
+// Platform returns the oldest platform that defines the instruction.
+// Opcodes that are undefined everywhere are reported as CHIP-8.
+func (i Instruction) Platform() Platform {
+	switch i.Kind {
+	case 0x0:
+		switch {
+		case i.Opcode == 0x0010 || i.Opcode == 0x0011: // megaoff, megaon
+			return PlatformMEGACHIP
+		case i.X == 0x0 && i.Y == 0xC: // 00CN scroll down
+			return PlatformSCHIP
+		case i.X == 0x0 && i.Y == 0xD: // 00DN scroll up
+			return PlatformXOCHIP
+		case i.Opcode >= 0x00FB && i.Opcode <= 0x00FF: // scroll, exit, lores, hires
+			return PlatformSCHIP
+		}
+	case 0x5:
+		if i.N == 0x2 || i.N == 0x3 { // save/load vx - vy
+			return PlatformXOCHIP
+		}
+	case 0xD:
+		if i.N == 0x0 { // 16x16 sprite
+			return PlatformSCHIP
+		}
+	case 0xF:
+		switch {
+		case i.Opcode == 0xF000 || i.Opcode == 0xF002: // long I, audio pattern
+			return PlatformXOCHIP
+		case i.NN == 0x01: // select plane
+			return PlatformXOCHIP
+		case i.NN == 0x3A: // pitch
+			return PlatformXOCHIP
+		case i.NN == 0x30 || i.NN == 0x75 || i.NN == 0x85: // big font, flags
+			return PlatformSCHIP
+		}
+	}
+
+	return PlatformCHIP8
+}
@@ -0,0 +1,71 @@
+package decoder
+
+import "testing"
+
+func TestDecode(t *testing.T) {
+	i := Decode(0xD1, 0x2F)
+
+	want := Instruction{Opcode: 0xD12F, Kind: 0xD, X: 0x1, Y: 0x2, N: 0xF, NN: 0x2F, NNN: 0x12F}
+	if i != want {
+		t.Errorf("Decode(D12F) = %+v, want %+v", i, want)
+	}
+}
+
+func TestPlatform(t *testing.T) {
+	tests := []struct {
+		opcode uint16
+		want   Platform
+	}{
+		{0x00E0, PlatformCHIP8},
+		{0x00EE, PlatformCHIP8},
+		{0xD125, PlatformCHIP8},
+		{0x5120, PlatformCHIP8},
+		{0xF129, PlatformCHIP8},
+		{0x00C4, PlatformSCHIP},
+		{0x00FB, PlatformSCHIP},
+		{0x00FF, PlatformSCHIP},
+		{0xD120, PlatformSCHIP},
+		{0xF130, PlatformSCHIP},
+		{0xF175, PlatformSCHIP},
+		{0xF185, PlatformSCHIP},
+		{0x00D4, PlatformXOCHIP},
+		{0x5122, PlatformXOCHIP},
+		{0x5123, PlatformXOCHIP},
+		{0xF000, PlatformXOCHIP},
+		{0xF002, PlatformXOCHIP},
+		{0xF201, PlatformXOCHIP},
+		{0xF13A, PlatformXOCHIP},
+		{0x0010, PlatformMEGACHIP},
+		{0x0011, PlatformMEGACHIP},
+	}
+
+	for _, tt := range tests {
+		i := Decode(byte(tt.opcode>>8), byte(tt.opcode))
+		if got := i.Platform(); got != tt.want {
+			t.Errorf("%04X platform = %s, want %s", tt.opcode, got, tt.want)
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	if s := Decode(0xF0, 0x00).Size(); s != 4 {
+		t.Errorf("F000 size = %d, want 4", s)
+	}
+
+	if s := Decode(0xF1, 0x00).Size(); s != 2 {
+		t.Errorf("F100 size = %d, want 2", s)
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	for _, p := range []Platform{PlatformCHIP8, PlatformSCHIP, PlatformXOCHIP, PlatformMEGACHIP} {
+		got, err := ParsePlatform(" " + p.String() + " ")
+		if err != nil || got != p {
+			t.Errorf("ParsePlatform(%q) = %s, %v", p.String(), got, err)
+		}
+	}
+
+	if _, err := ParsePlatform("gameboy"); err == nil {
+		t.Error("ParsePlatform(gameboy) did not fail")
+	}
+}
@@ -0,0 +1,138 @@
+package decoder
+
+import "fmt"
+
+// Mnemonic disassembles the instruction using Cowgod's names, ex: LD V3, 0x10.
+// Opcodes that are undefined on every platform are shown as raw data.
+func (i Instruction) Mnemonic() string {
+	switch i.Kind {
+	case 0x0:
+		switch {
+		case i.Opcode == 0x00E0:
+			return "CLS"
+		case i.Opcode == 0x00EE:
+			return "RET"
+		case i.Opcode == 0x00FB:
+			return "SCR"
+		case i.Opcode == 0x00FC:
+			return "SCL"
+		case i.Opcode == 0x00FD:
+			return "EXIT"
+		case i.Opcode == 0x00FE:
+			return "LOW"
+		case i.Opcode == 0x00FF:
+			return "HIGH"
+		case i.Opcode == 0x0010:
+			return "MEGAOFF"
+		case i.Opcode == 0x0011:
+			return "MEGAON"
+		case i.X == 0x0 && i.Y == 0xC:
+			return fmt.Sprintf("SCD %d", i.N)
+		case i.X == 0x0 && i.Y == 0xD:
+			return fmt.Sprintf("SCU %d", i.N)
+		}
+
+		return fmt.Sprintf("SYS 0x%03X", i.NNN)
+	case 0x1:
+		return fmt.Sprintf("JP 0x%03X", i.NNN)
+	case 0x2:
+		return fmt.Sprintf("CALL 0x%03X", i.NNN)
+	case 0x3:
+		return fmt.Sprintf("SE V%X, 0x%02X", i.X, i.NN)
+	case 0x4:
+		return fmt.Sprintf("SNE V%X, 0x%02X", i.X, i.NN)
+	case 0x5:
+		switch i.N {
+		case 0x0:
+			return fmt.Sprintf("SE V%X, V%X", i.X, i.Y)
+		case 0x2:
+			return fmt.Sprintf("SAVE V%X-V%X", i.X, i.Y)
+		case 0x3:
+			return fmt.Sprintf("LOAD V%X-V%X", i.X, i.Y)
+		}
+	case 0x6:
+		return fmt.Sprintf("LD V%X, 0x%02X", i.X, i.NN)
+	case 0x7:
+		return fmt.Sprintf("ADD V%X, 0x%02X", i.X, i.NN)
+	case 0x8:
+		switch i.N {
+		case 0x0:
+			return fmt.Sprintf("LD V%X, V%X", i.X, i.Y)
+		case 0x1:
+			return fmt.Sprintf("OR V%X, V%X", i.X, i.Y)
+		case 0x2:
+			return fmt.Sprintf("AND V%X, V%X", i.X, i.Y)
+		case 0x3:
+			return fmt.Sprintf("XOR V%X, V%X", i.X, i.Y)
+		case 0x4:
+			return fmt.Sprintf("ADD V%X, V%X", i.X, i.Y)
+		case 0x5:
+			return fmt.Sprintf("SUB V%X, V%X", i.X, i.Y)
+		case 0x6:
+			return fmt.Sprintf("SHR V%X, V%X", i.X, i.Y)
+		case 0x7:
+			return fmt.Sprintf("SUBN V%X, V%X", i.X, i.Y)
+		case 0xE:
+			return fmt.Sprintf("SHL V%X, V%X", i.X, i.Y)
+		}
+	case 0x9:
+		if i.N == 0x0 {
+			return fmt.Sprintf("SNE V%X, V%X", i.X, i.Y)
+		}
+	case 0xA:
+		return fmt.Sprintf("LD I, 0x%03X", i.NNN)
+	case 0xB:
+		return fmt.Sprintf("JP V0, 0x%03X", i.NNN)
+	case 0xC:
+		return fmt.Sprintf("RND V%X, 0x%02X", i.X, i.NN)
+	case 0xD:
+		return fmt.Sprintf("DRW V%X, V%X, %d", i.X, i.Y, i.N)
+	case 0xE:
+		switch i.NN {
+		case 0x9E:
+			return fmt.Sprintf("SKP V%X", i.X)
+		case 0xA1:
+			return fmt.Sprintf("SKNP V%X", i.X)
+		}
+	case 0xF:
+		switch {
+		case i.Opcode == 0xF000:
+			return "LD I, long"
+		case i.Opcode == 0xF002:
+			return "AUDIO"
+		}
+
+		switch i.NN {
+		case 0x01:
+			return fmt.Sprintf("PLANE %d", i.X)
+		case 0x07:
+			return fmt.Sprintf("LD V%X, DT", i.X)
+		case 0x0A:
+			return fmt.Sprintf("LD V%X, K", i.X)
+		case 0x15:
+			return fmt.Sprintf("LD DT, V%X", i.X)
+		case 0x18:
+			return fmt.Sprintf("LD ST, V%X", i.X)
+		case 0x1E:
+			return fmt.Sprintf("ADD I, V%X", i.X)
+		case 0x29:
+			return fmt.Sprintf("LD F, V%X", i.X)
+		case 0x30:
+			return fmt.Sprintf("LD HF, V%X", i.X)
+		case 0x33:
+			return fmt.Sprintf("LD B, V%X", i.X)
+		case 0x3A:
+			return fmt.Sprintf("PITCH V%X", i.X)
+		case 0x55:
+			return fmt.Sprintf("LD [I], V%X", i.X)
+		case 0x65:
+			return fmt.Sprintf("LD V%X, [I]", i.X)
+		case 0x75:
+			return fmt.Sprintf("LD R, V%X", i.X)
+		case 0x85:
+			return fmt.Sprintf("LD V%X, R", i.X)
+		}
+	}
+
+	return fmt.Sprintf("DW 0x%04X", i.Opcode)
+}
@@ -0,0 +1,178 @@
+package detect
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/otaviohenrique/zamorak/pkg/decoder"
+)
+
+var (
+	// Programs are loaded at the same place the interpreter loads them.
+	MEMORY_OFFSET = 0x200
+
+	// Space left for a program once the interpreter area is reserved.
+	MAX_PROGRAM_SIZE = 4096 - MEMORY_OFFSET
+)
+
+// Finding is a reachable instruction that only exists on a newer platform.
+type Finding struct {
+	Address     uint16
+	Instruction decoder.Instruction
+	Platform    decoder.Platform
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%04X at 0x%03X (%s)", f.Instruction.Opcode, f.Address, f.Platform)
+}
+
+// Report is the result of scanning a program.
+type Report struct {
+	Platform  decoder.Platform // best guess for the platform the program targets
+	Findings  []Finding        // reachable opcodes that are not plain CHIP-8
+	Size      int              // program size in bytes
+	Oversized bool             // program does not fit in 4 KiB of memory
+}
+
+// Suggestion returns a short human readable explanation of the report, or an
+// empty string when the program looks like plain CHIP-8.
+func (r Report) Suggestion() string {
+	if r.Platform == decoder.PlatformCHIP8 {
+		return ""
+	}
+
+	reasons := []string{}
+
+	if r.Oversized {
+		reasons = append(reasons, fmt.Sprintf("program is %d bytes, more than the %d bytes available", r.Size, MAX_PROGRAM_SIZE))
+	}
+
+	for i, f := range r.Findings {
+		if i == 3 {
+			reasons = append(reasons, fmt.Sprintf("%d more", len(r.Findings)-i))
+			break
+		}
+
+		reasons = append(reasons, f.String())
+	}
+
+	return fmt.Sprintf("program looks like %s (%s), try --profile %s", r.Platform, strings.Join(reasons, ", "), r.Platform)
+}
+
+// Scan walks every instruction reachable from the program entry point and
+// reports the ones that need a platform newer than CHIP-8. Data is never
+// decoded as long as it is not reachable through jumps, calls and skips.
+// Computed jumps (BNNN) are followed to NNN only, as their offset is not known
+// until runtime.
+func Scan(program []byte) Report {
+	report := Report{
+		Platform:  decoder.PlatformCHIP8,
+		Size:      len(program),
+		Oversized: len(program) > MAX_PROGRAM_SIZE,
+	}
+
+	end := MEMORY_OFFSET + len(program)
+	visited := make(map[int]bool)
+	pending := []int{MEMORY_OFFSET}
+
+	fetch := func(addr int) (decoder.Instruction, bool) {
+		if addr < MEMORY_OFFSET || addr+1 >= end {
+			return decoder.Instruction{}, false
+		}
+
+		i := addr - MEMORY_OFFSET
+
+		return decoder.Decode(program[i], program[i+1]), true
+	}
+
+	for len(pending) > 0 {
+		addr := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if visited[addr] {
+			continue
+		}
+		visited[addr] = true
+
+		instr, ok := fetch(addr)
+		if !ok {
+			continue
+		}
+
+		if p := instr.Platform(); p != decoder.PlatformCHIP8 {
+			report.Findings = append(report.Findings, Finding{Address: uint16(addr), Instruction: instr, Platform: p})
+		}
+
+		next := addr + int(instr.Size())
+
+		switch {
+		case instr.Opcode == 0x00EE || instr.Opcode == 0x00FD: // return, exit
+		case instr.Kind == 0x1:
+			pending = append(pending, int(instr.NNN))
+		case instr.Kind == 0x2:
+			pending = append(pending, next, int(instr.NNN))
+		case instr.Kind == 0xB:
+			pending = append(pending, int(instr.NNN))
+		case isSkip(instr):
+			pending = append(pending, next)
+
+			if skipped, ok := fetch(next); ok {
+				pending = append(pending, next+int(skipped.Size()))
+			}
+		default:
+			pending = append(pending, next)
+		}
+	}
+
+	sort.Slice(report.Findings, func(i, j int) bool {
+		return report.Findings[i].Address < report.Findings[j].Address
+	})
+
+	report.Platform = guessPlatform(report)
+
+	return report
+}
+
+func isSkip(instr decoder.Instruction) bool {
+	switch instr.Kind {
+	case 0x3, 0x4:
+		return true
+	case 0x5, 0x9:
+		return instr.N == 0x0
+	case 0xE:
+		return instr.NN == 0x9E || instr.NN == 0xA1
+	}
+
+	return false
+}
+
+func guessPlatform(r Report) decoder.Platform {
+	seen := make(map[decoder.Platform]bool)
+	for _, f := range r.Findings {
+		seen[f.Platform] = true
+	}
+
+	switch {
+	case seen[decoder.PlatformMEGACHIP]:
+		return decoder.PlatformMEGACHIP
+	case seen[decoder.PlatformXOCHIP]:
+		return decoder.PlatformXOCHIP
+	case seen[decoder.PlatformSCHIP]:
+		return decoder.PlatformSCHIP
+	case r.Oversized:
+		return decoder.PlatformXOCHIP
+	}
+
+	return decoder.PlatformCHIP8
+}
+
+// Hash returns the SHA-1 of a program as lowercase hex, the key ROM databases
+// use to identify programs.
+func Hash(programData []byte) string {
+	sum := sha1.Sum(programData)
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,145 @@
+package detect
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/otaviohenrique/zamorak/pkg/decoder"
+)
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name     string
+		program  []byte
+		platform decoder.Platform
+		findings []uint16 // addresses
+	}{
+		{
+			name: "chip8",
+			program: []byte{
+				0x60, 0x01, // 6001
+				0xA2, 0x08, // A208
+				0xD0, 0x15, // D015
+				0x12, 0x06, // 1206: loop
+			},
+			platform: decoder.PlatformCHIP8,
+		},
+		{
+			name: "schip hires",
+			program: []byte{
+				0x00, 0xFF, // 00FF: hires
+				0xD0, 0x10, // D010: 16x16 sprite
+				0x12, 0x04, // 1204: loop
+			},
+			platform: decoder.PlatformSCHIP,
+			findings: []uint16{0x200, 0x202},
+		},
+		{
+			name: "xochip long I",
+			program: []byte{
+				0xF0, 0x00, 0x03, 0x00, // F000 0300: I = 0x300
+				0x00, 0xC1, // 00C1: schip scroll down
+				0xF1, 0x01, // F101: plane 1
+				0x12, 0x08, // 1208: loop
+			},
+			platform: decoder.PlatformXOCHIP,
+			findings: []uint16{0x200, 0x204, 0x206},
+		},
+		{
+			name: "megachip",
+			program: []byte{
+				0x00, 0x11, // 0011: megaon
+				0x00, 0xFF, // 00FF: hires
+				0x12, 0x04, // 1204: loop
+			},
+			platform: decoder.PlatformMEGACHIP,
+			findings: []uint16{0x200, 0x202},
+		},
+		{
+			name: "unreachable behind a jump",
+			program: []byte{
+				0x12, 0x06, // 1206: jump over the data
+				0x00, 0xFF, // sprite data that reads as 00FF
+				0xF0, 0x01, // and as F001
+				0x60, 0x01, // 6001
+				0x12, 0x08, // 1208: loop
+			},
+			platform: decoder.PlatformCHIP8,
+		},
+		{
+			name: "call and skip",
+			program: []byte{
+				0x22, 0x08, // 2208: call
+				0x30, 0x00, // 3000: skip when V0 is 0
+				0x00, 0xFE, // 00FE: lores, reachable when not skipped
+				0x12, 0x06, // 1206: loop
+				0x00, 0xFB, // 00FB: scroll right, in the subroutine
+				0x00, 0xEE, // 00EE: return
+				0x00, 0xFF, // 00FF: after the return, unreachable
+			},
+			platform: decoder.PlatformSCHIP,
+			findings: []uint16{0x204, 0x208},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Scan(tt.program)
+
+			if r.Platform != tt.platform {
+				t.Errorf("platform = %s, want %s", r.Platform, tt.platform)
+			}
+
+			if len(r.Findings) != len(tt.findings) {
+				t.Fatalf("findings = %v, want at %03X", r.Findings, tt.findings)
+			}
+
+			for i, f := range r.Findings {
+				if f.Address != tt.findings[i] {
+					t.Errorf("finding %d at %03X, want %03X", i, f.Address, tt.findings[i])
+				}
+			}
+
+			if got := r.Suggestion(); (got == "") != (tt.platform == decoder.PlatformCHIP8) {
+				t.Errorf("suggestion = %q", got)
+			}
+		})
+	}
+}
+
+func TestScanOversized(t *testing.T) {
+	program := make([]byte, MAX_PROGRAM_SIZE+2)
+	program[0], program[1] = 0x12, 0x00 // 1200: loop
+
+	r := Scan(program)
+
+	if !r.Oversized || r.Platform != decoder.PlatformXOCHIP {
+		t.Fatalf("oversized, platform = %v, %s, want true, xochip", r.Oversized, r.Platform)
+	}
+
+	if got := r.Suggestion(); !strings.Contains(got, "--profile xochip") || !strings.Contains(got, "more than the") {
+		t.Errorf("suggestion = %q, want the size and --profile xochip", got)
+	}
+
+	if r := Scan(program[:MAX_PROGRAM_SIZE]); r.Oversized {
+		t.Errorf("%d bytes reported as oversized", MAX_PROGRAM_SIZE)
+	}
+}
+
+func TestSuggestionListsThreeFindings(t *testing.T) {
+	program := []byte{
+		0x00, 0xFF, // 00FF
+		0x00, 0xFE, // 00FE
+		0x00, 0xFB, // 00FB
+		0x00, 0xFC, // 00FC
+		0x00, 0xC1, // 00C1
+		0x12, 0x0A, // 120A: loop
+	}
+
+	got := Scan(program).Suggestion()
+
+	want := "program looks like schip (00FF at 0x200 (schip), 00FE at 0x202 (schip), 00FB at 0x204 (schip), 2 more), try --profile schip"
+	if got != want {
+		t.Errorf("suggestion = %q, want %q", got, want)
+	}
+}
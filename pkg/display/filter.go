@@ -0,0 +1,97 @@
+package display
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	// none draws pixels as soon as they change, decay fades pixels out like
+	// phosphor, blend mixes in the previous frame and vblank only shows
+	// complete frames
+	FILTERS = []string{"none", "decay", "blend", "vblank"}
+
+	DEFAULT_FILTER_STRENGTH = 0.75
+)
+
+// Filter fights the flicker of sprites erased and drawn again every frame. It
+// is fed the framebuffer once per frame, at vertical blank, and gives the
+// brightness every pixel is to be drawn with, from 0 to 1.
+type Filter struct {
+	mode      string
+	strength  float64
+	width     int
+	intensity []float64
+	previous  []bool // pixels lit at the previous vblank
+}
+
+// NewFilter returns a filter for a width x height screen. strength goes from
+// 0 to 1: how much of a pixel is left after a frame with decay, and how
+// bright the previous frame is drawn with blend. vblank ignores it.
+func NewFilter(mode string, strength float64, width, height int) (*Filter, error) {
+	f := new(Filter)
+
+	known := false
+	for _, m := range FILTERS {
+		known = known || m == mode
+	}
+
+	if !known {
+		return nil, fmt.Errorf("unknown display filter %q, want one of %s", mode, strings.Join(FILTERS, ", "))
+	}
+
+	if strength < 0 || strength > 1 {
+		return nil, fmt.Errorf("filter strength must be between 0 and 1, got %v", strength)
+	}
+
+	f.mode = mode
+	f.strength = strength
+	f.width = width
+	f.intensity = make([]float64, width*height)
+	f.previous = make([]bool, width*height)
+
+	return f, nil
+}
+
+func (f *Filter) Mode() string {
+	return f.mode
+}
+
+// Latch takes the framebuffer as it is at the end of a frame.
+func (f *Filter) Latch(fb *Framebuffer) {
+	for i, on := range fb.pixels {
+		var lit float64
+		if on {
+			lit = 1
+		}
+
+		switch f.mode {
+		case "decay":
+			f.intensity[i] = max(lit, f.intensity[i]*f.strength)
+		case "blend":
+			var previous float64
+			if f.previous[i] {
+				previous = f.strength
+			}
+
+			f.intensity[i] = max(lit, previous)
+		default:
+			f.intensity[i] = lit
+		}
+
+		f.previous[i] = on
+	}
+}
+
+// Intensity returns how bright a pixel is drawn, from 0 to 1.
+func (f *Filter) Intensity(col int, row int) float64 {
+	return f.intensity[row*f.width+col]
+}
+
+// Reset forgets the previous frames, for when the screen is cleared.
+func (f *Filter) Reset() {
+	for i := range f.intensity {
+		f.intensity[i] = 0
+		f.previous[i] = false
+	}
+}
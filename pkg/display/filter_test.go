@@ -0,0 +1,75 @@
+package display
+
+import (
+	"math"
+	"testing"
+)
+
+// frames latches one frame per pattern, a pattern says whether pixel 0, 0 is
+// lit. Every frame starts with a clear, like a game redrawing its sprites
+// with 00E0 every frame.
+func frames(f *Filter, fb *Framebuffer, lit ...bool) {
+	for _, on := range lit {
+		fb.ClearScreen()
+		fb.Set(0, 0, on)
+		f.Latch(fb)
+	}
+}
+
+func TestFilterAcrossClears(t *testing.T) {
+	tests := []struct {
+		mode string
+		lit  []bool
+		want float64
+	}{
+		{"none", []bool{true, false}, 0},
+		{"vblank", []bool{true, false}, 0},
+		{"decay", []bool{true, false}, 0.5},
+		{"decay", []bool{true, false, false}, 0.25},
+		{"decay", []bool{true, false, true}, 1},
+		{"blend", []bool{true, false}, 0.5},
+		{"blend", []bool{true, false, false}, 0},
+		{"blend", []bool{false, true}, 1},
+	}
+
+	for _, tt := range tests {
+		f, err := NewFilter(tt.mode, 0.5, 4, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		frames(f, NewFramebuffer(4, 2), tt.lit...)
+
+		if got := f.Intensity(0, 0); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s after %v: intensity %v, want %v", tt.mode, tt.lit, got, tt.want)
+		}
+
+		// pixels never lit stay dark
+		if got := f.Intensity(3, 1); got != 0 {
+			t.Errorf("%s: unlit pixel has intensity %v", tt.mode, got)
+		}
+	}
+}
+
+func TestFilterReset(t *testing.T) {
+	f, _ := NewFilter("decay", 0.5, 1, 1)
+	fb := NewFramebuffer(1, 1)
+
+	frames(f, fb, true)
+	f.Reset()
+	frames(f, fb, false)
+
+	if got := f.Intensity(0, 0); got != 0 {
+		t.Errorf("intensity %v after a reset, want 0", got)
+	}
+}
+
+func TestNewFilterErrors(t *testing.T) {
+	if _, err := NewFilter("crt", 0.5, 1, 1); err == nil {
+		t.Error("unknown mode accepted")
+	}
+
+	if _, err := NewFilter("decay", 1.5, 1, 1); err == nil {
+		t.Error("strength above 1 accepted")
+	}
+}
@@ -0,0 +1,88 @@
+package display
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"strings"
+)
+
+// Framebuffer holds which pixels of the screen are lit, independently of how
+// they are presented.
+type Framebuffer struct {
+	width  int
+	height int
+	pixels []bool
+}
+
+func NewFramebuffer(width, height int) *Framebuffer {
+	f := new(Framebuffer)
+
+	f.width = width
+	f.height = height
+	f.pixels = make([]bool, width*height)
+
+	return f
+}
+
+func (f *Framebuffer) Width() int {
+	return f.width
+}
+
+func (f *Framebuffer) Height() int {
+	return f.height
+}
+
+func (f *Framebuffer) IsPixelSet(col int, row int) bool {
+	return f.pixels[row*f.width+col]
+}
+
+func (f *Framebuffer) Set(col int, row int, on bool) {
+	f.pixels[row*f.width+col] = on
+}
+
+func (f *Framebuffer) ClearScreen() {
+	for i := range f.pixels {
+		f.pixels[i] = false
+	}
+}
+
+// Hash returns a 64 bit FNV-1a hash of the screen size and lit pixels, good
+// enough to tell two frames apart.
+func (f *Framebuffer) Hash() uint64 {
+	h := fnv.New64a()
+
+	var size [4]byte
+	binary.BigEndian.PutUint16(size[0:], uint16(f.width))
+	binary.BigEndian.PutUint16(size[2:], uint16(f.height))
+	h.Write(size[:])
+
+	packed := make([]byte, (len(f.pixels)+7)/8)
+	for i, on := range f.pixels {
+		if on {
+			packed[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	h.Write(packed)
+
+	return h.Sum64()
+}
+
+// String draws the screen as text, one line per row, with # for lit pixels
+// and . for the others.
+func (f *Framebuffer) String() string {
+	var b strings.Builder
+
+	for row := 0; row < f.height; row++ {
+		for col := 0; col < f.width; col++ {
+			if f.IsPixelSet(col, row) {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
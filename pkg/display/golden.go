@@ -0,0 +1,129 @@
+package display
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// ParseText reads a screen written by String: one line per row, with # for
+// lit pixels and . for the others.
+func ParseText(data []byte) (*Framebuffer, error) {
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"), "\n")
+
+	if len(lines) == 0 || len(lines[0]) == 0 {
+		return nil, fmt.Errorf("empty screen")
+	}
+
+	f := NewFramebuffer(len(lines[0]), len(lines))
+
+	for row, line := range lines {
+		if len(line) != f.Width() {
+			return nil, fmt.Errorf("line %d is %d pixels wide, expected %d", row+1, len(line), f.Width())
+		}
+
+		for col, c := range line {
+			switch c {
+			case '#':
+				f.Set(col, row, true)
+			case '.':
+			default:
+				return nil, fmt.Errorf("line %d: unexpected %q", row+1, c)
+			}
+		}
+	}
+
+	return f, nil
+}
+
+// PNG encodes the screen at its native size, white on black.
+func (f *Framebuffer) PNG() []byte {
+	img := image.NewGray(image.Rect(0, 0, f.width, f.height))
+
+	for row := 0; row < f.height; row++ {
+		for col := 0; col < f.width; col++ {
+			if f.IsPixelSet(col, row) {
+				img.SetGray(col, row, color.Gray{Y: 0xFF})
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	png.Encode(&b, img)
+
+	return b.Bytes()
+}
+
+// DecodePNG reads a screen from a native size PNG image. Any bright pixel is
+// lit, whatever the palette it was saved with.
+func DecodePNG(data []byte) (*Framebuffer, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	f := NewFramebuffer(bounds.Dx(), bounds.Dy())
+
+	for row := 0; row < f.height; row++ {
+		for col := 0; col < f.width; col++ {
+			gray := color.GrayModel.Convert(img.At(bounds.Min.X+col, bounds.Min.Y+row)).(color.Gray)
+			f.Set(col, row, gray.Y >= 0x80)
+		}
+	}
+
+	return f, nil
+}
+
+// Diff returns an empty string when both screens are the same, or the rows
+// that differ otherwise, marking the pixels that are wrong with ^.
+func Diff(want, got *Framebuffer) string {
+	if want.width != got.width || want.height != got.height {
+		return fmt.Sprintf("want a %dx%d screen, got %dx%d", want.width, want.height, got.width, got.height)
+	}
+
+	wantRows := strings.Split(want.String(), "\n")
+	gotRows := strings.Split(got.String(), "\n")
+
+	var b strings.Builder
+
+	for row := 0; row < want.height; row++ {
+		if wantRows[row] == gotRows[row] {
+			continue
+		}
+
+		marks := make([]byte, want.width)
+		for col := range marks {
+			marks[col] = ' '
+			if wantRows[row][col] != gotRows[row][col] {
+				marks[col] = '^'
+			}
+		}
+
+		fmt.Fprintf(&b, "row %2d want %s\n       got  %s\n            %s\n", row, wantRows[row], gotRows[row], strings.TrimRight(string(marks), " "))
+	}
+
+	return b.String()
+}
+
+// ReadGolden reads a screen saved as text, when name ends in .txt, or as a
+// PNG image otherwise.
+func ReadGolden(name string, data []byte) (*Framebuffer, error) {
+	if strings.HasSuffix(name, ".txt") {
+		return ParseText(data)
+	}
+
+	return DecodePNG(data)
+}
+
+// EncodeGolden encodes the screen in the format ReadGolden expects for name.
+func (f *Framebuffer) EncodeGolden(name string) []byte {
+	if strings.HasSuffix(name, ".txt") {
+		return []byte(f.String())
+	}
+
+	return f.PNG()
+}
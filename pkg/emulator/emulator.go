@@ -0,0 +1,334 @@
+package emulator
+
+import (
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+	"github.com/otaviohenrique/zamorak/pkg/logger"
+)
+
+var (
+	// speed steps of SpeedUp and SpeedDown
+	SPEEDS = []float64{0.25, 0.5, 1, 2, 4, 8}
+
+	// default speed while fast forwarding
+	FAST_FORWARD_SPEED = 8.0
+
+	// slow motion runs the game this much slower than the current speed
+	SLOW_MOTION_FACTOR = 0.25
+)
+
+// Emulator drives a Chip8 from a frontend loop: the frontend calls Update
+// once per host frame and the emulator decides how many CHIP-8 frames that
+// is worth, depending on whether it is paused and on its speed. It also owns
+// the program, so it can reset the machine or reload the ROM from disk.
+//
+// Every method except Queue must be called from the goroutine running
+// Update.
+type Emulator struct {
+	base    *slog.Logger // handed to every new machine
+	log     *slog.Logger
+	quirks  interpreter.Quirks
+	path    string
+	program []byte
+	runtime interpreter.Runtime
+	chip8   *interpreter.Chip8
+	hooks   []interpreter.Hook
+	ipf     int
+	speed   float64
+	ffSpeed float64
+	ff      bool    // fast forwarding
+	slow    bool    // slow motion
+	owed    float64 // frames owed when running slower than real time
+	seed    int64
+	seeded  bool // machines are seeded with seed instead of the time
+	paused  bool
+	step    bool  // run a single frame while paused
+	stopped error // why the program stopped, nil while it runs
+	onStop  func(err error)
+	onFrame func()
+	onReset func()
+	mu      sync.Mutex
+	queue   []func(c *interpreter.Chip8)
+}
+
+func New(log *slog.Logger, quirks interpreter.Quirks, path string, programData []byte, runtime interpreter.Runtime) (*Emulator, error) {
+	e := new(Emulator)
+
+	e.base = log
+	e.log = logger.For(log, logger.SCHEDULER)
+	e.quirks = quirks
+	e.path = path
+	e.program = programData
+	e.runtime = runtime
+	e.ipf = interpreter.INSTRUCTIONS_PER_FRAME
+	e.speed = 1
+	e.ffSpeed = FAST_FORWARD_SPEED
+
+	if err := e.Reset(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Path returns the file the ROM was read from.
+func (e *Emulator) Path() string {
+	return e.path
+}
+
+// Chip8 returns the current machine, which is replaced on every reset.
+func (e *Emulator) Chip8() *interpreter.Chip8 {
+	return e.chip8
+}
+
+// AddHook installs a hook on the current machine and on the ones created by
+// later resets.
+func (e *Emulator) AddHook(h interpreter.Hook) {
+	e.hooks = append(e.hooks, h)
+	e.chip8.AddHook(h)
+}
+
+// OnStop sets a function called once every time the program halts or faults.
+func (e *Emulator) OnStop(fn func(err error)) {
+	e.onStop = fn
+}
+
+// OnFrame sets a function called at the end of every frame, at vertical
+// blank.
+func (e *Emulator) OnFrame(fn func()) {
+	e.onFrame = fn
+}
+
+// OnReset sets a function called every time the program starts over on a new
+// machine, after the screen is cleared.
+func (e *Emulator) OnReset(fn func()) {
+	e.onReset = fn
+}
+
+// Queue runs fn before the next frame, on the goroutine running Update, even
+// while paused or stopped. It is safe to call from any goroutine.
+func (e *Emulator) Queue(fn func(c *interpreter.Chip8)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.queue = append(e.queue, fn)
+}
+
+// Update runs as many frames as one host frame is worth.
+func (e *Emulator) Update() {
+	e.mu.Lock()
+	queue := e.queue
+	e.queue = nil
+	e.mu.Unlock()
+
+	for _, fn := range queue {
+		fn(e.chip8)
+	}
+
+	if e.stopped != nil {
+		return
+	}
+
+	if e.paused {
+		if e.step {
+			e.step = false
+			e.frame()
+		}
+
+		return
+	}
+
+	e.owed += e.EffectiveSpeed()
+	for ; e.owed >= 1 && e.stopped == nil; e.owed-- {
+		e.frame()
+	}
+}
+
+func (e *Emulator) frame() {
+	err := e.chip8.Frame(e.runtime, e.ipf)
+
+	if e.onFrame != nil {
+		e.onFrame()
+	}
+
+	if err == nil {
+		return
+	}
+
+	e.stopped = err
+
+	if e.onStop != nil {
+		e.onStop(err)
+	}
+}
+
+// Stopped returns why the program stopped, or nil while it runs.
+func (e *Emulator) Stopped() error {
+	return e.stopped
+}
+
+func (e *Emulator) Paused() bool {
+	return e.paused
+}
+
+func (e *Emulator) SetPaused(paused bool) {
+	if paused {
+		e.runtime.StopAudio()
+	}
+
+	e.paused = paused
+}
+
+// Step runs one frame on the next Update, while paused.
+func (e *Emulator) Step() {
+	e.step = true
+}
+
+// Seed makes the random numbers of the current machine and of the ones
+// created by later resets the same on every run.
+func (e *Emulator) Seed(seed int64) {
+	e.seed = seed
+	e.seeded = true
+
+	e.chip8.Seed(seed)
+}
+
+// Reset starts the program over on a new machine. The screen is cleared like
+// on power up, the keypad is left as it is.
+func (e *Emulator) Reset() error {
+	c := interpreter.NewChip8(e.base, e.quirks)
+
+	if e.seeded {
+		c.Seed(e.seed)
+	}
+
+	if err := c.Load(e.program); err != nil {
+		return err
+	}
+
+	for _, h := range e.hooks {
+		c.AddHook(h)
+	}
+
+	e.chip8 = c
+	e.stopped = nil
+	e.owed = 0
+
+	e.runtime.ClearScreen()
+	e.runtime.StopAudio()
+
+	if e.onReset != nil {
+		e.onReset()
+	}
+
+	return nil
+}
+
+// Reload reads the ROM from disk again and resets, for when it was rebuilt.
+func (e *Emulator) Reload() error {
+	programData, err := os.ReadFile(e.path)
+	if err != nil {
+		return err
+	}
+
+	previous := e.program
+	e.program = programData
+
+	if err := e.Reset(); err != nil {
+		e.program = previous
+
+		return err
+	}
+
+	return nil
+}
+
+// IPF returns the instructions run per frame.
+func (e *Emulator) IPF() int {
+	return e.ipf
+}
+
+func (e *Emulator) SetIPF(ipf int) {
+	if ipf > 0 {
+		e.ipf = ipf
+	}
+}
+
+func (e *Emulator) Speed() float64 {
+	return e.speed
+}
+
+// EffectiveSpeed is the speed frames actually run at, once fast forward and
+// slow motion are taken into account.
+func (e *Emulator) EffectiveSpeed() float64 {
+	switch {
+	case e.ff:
+		return e.ffSpeed
+	case e.slow:
+		return e.speed * SLOW_MOTION_FACTOR
+	}
+
+	return e.speed
+}
+
+// SetFastForward runs the game at the fast forward speed while on.
+func (e *Emulator) SetFastForward(on bool) {
+	e.ff = on
+}
+
+func (e *Emulator) FastForward() bool {
+	return e.ff
+}
+
+// SetFastForwardSpeed sets how fast fast forward runs.
+func (e *Emulator) SetFastForwardSpeed(speed float64) {
+	if speed > 0 {
+		e.ffSpeed = speed
+	}
+}
+
+// SetSlowMotion runs the game SLOW_MOTION_FACTOR times the speed while on.
+func (e *Emulator) SetSlowMotion(on bool) {
+	e.slow = on
+	e.owed = 0
+}
+
+func (e *Emulator) SlowMotion() bool {
+	return e.slow
+}
+
+// SetSpeed runs speed frames per host frame, fractions run slower than real
+// time.
+func (e *Emulator) SetSpeed(speed float64) {
+	if speed <= 0 {
+		return
+	}
+
+	e.speed = speed
+	e.owed = 0
+}
+
+// SpeedUp moves to the next faster step of SPEEDS.
+func (e *Emulator) SpeedUp() {
+	for _, s := range SPEEDS {
+		if s > e.speed {
+			e.SetSpeed(s)
+
+			return
+		}
+	}
+}
+
+// SpeedDown moves to the next slower step of SPEEDS.
+func (e *Emulator) SpeedDown() {
+	for i := len(SPEEDS) - 1; i >= 0; i-- {
+		if SPEEDS[i] < e.speed {
+			e.SetSpeed(SPEEDS[i])
+
+			return
+		}
+	}
+}
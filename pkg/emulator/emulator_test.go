@@ -0,0 +1,41 @@
+package emulator
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/otaviohenrique/zamorak/pkg/headless"
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+)
+
+func TestOnResetIsNotCalledOnClear(t *testing.T) {
+	// clears the screen forever
+	program := []byte{0x00, 0xE0, 0x12, 0x00}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	e, err := New(log, interpreter.Quirks{}, "clear.ch8", program, headless.NewRuntime())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resets := 0
+	e.OnReset(func() { resets++ })
+
+	for i := 0; i < 3; i++ {
+		e.Update()
+	}
+
+	if resets != 0 {
+		t.Errorf("OnReset called %d times by 00E0, want 0", resets)
+	}
+
+	if err := e.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	if resets != 1 {
+		t.Errorf("OnReset called %d times by Reset, want 1", resets)
+	}
+}
@@ -0,0 +1,300 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/otaviohenrique/zamorak/pkg/capture"
+	"github.com/otaviohenrique/zamorak/pkg/keymap"
+)
+
+var (
+	// Emulator controls, on function keys so they do not clash with any
+	// keymap preset. Fast forward lasts while its key is held.
+	DEFAULT_HOTKEYS = map[string]string{
+		"pause":        "F1",
+		"step":         "F2",
+		"speed-down":   "F3",
+		"speed-up":     "F4",
+		"reset":        "F5",
+		"hard-reset":   "F6",
+		"slow-motion":  "F7",
+		"record":       "F8",
+		"fast-forward": "Tab",
+		"mute":         "F9",
+		"osd":          "F10",
+		"fullscreen":   "F11",
+		"screenshot":   "F12",
+	}
+)
+
+// Hotkeys binds emulator actions to host keys.
+type Hotkeys map[string]ebiten.Key
+
+// ParseHotkeys reads bindings written as action=Key, ex: pause=P,reset=F5,
+// on top of DEFAULT_HOTKEYS. An empty key unbinds the action. A key can only
+// be bound to one action.
+func ParseHotkeys(spec string) (Hotkeys, error) {
+	bindings := make(map[string]string)
+	for action, key := range DEFAULT_HOTKEYS {
+		bindings[action] = key
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		action, key, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid hotkey %q, want action=Key", part)
+		}
+
+		action = strings.ToLower(strings.TrimSpace(action))
+		if _, ok := DEFAULT_HOTKEYS[action]; !ok {
+			return nil, fmt.Errorf("unknown hotkey action %q, want one of %s", action, strings.Join(HotkeyActions(), ", "))
+		}
+
+		bindings[action] = strings.TrimSpace(key)
+	}
+
+	h := make(Hotkeys)
+	bound := make(map[ebiten.Key]string)
+
+	for _, action := range HotkeyActions() {
+		name := bindings[action]
+		if name == "" {
+			continue
+		}
+
+		var key ebiten.Key
+		if err := key.UnmarshalText([]byte(name)); err != nil {
+			return nil, fmt.Errorf("unknown key %q for hotkey %s", name, action)
+		}
+
+		// both actions would fire, unbind one with an empty key
+		if other, ok := bound[key]; ok {
+			return nil, fmt.Errorf("key %s is bound to both hotkeys %s and %s", name, other, action)
+		}
+
+		bound[key] = action
+		h[action] = key
+	}
+
+	return h, nil
+}
+
+// CheckKeymap returns an error when a hotkey is also bound to a CHIP-8 key by
+// a keymap, as both would fire at once. Keys the window does not know are
+// left to SetKeymap to report.
+func (h Hotkeys) CheckKeymap(k keymap.Keymap) error {
+	actions := make(map[ebiten.Key]string)
+	for action, key := range h {
+		actions[key] = action
+	}
+
+	for chip, names := range k {
+		for _, name := range names {
+			var key ebiten.Key
+			if err := key.UnmarshalText([]byte(name)); err != nil {
+				continue
+			}
+
+			if action, ok := actions[key]; ok {
+				return fmt.Errorf("key %s is bound to both hotkey %s and CHIP-8 key %X, rebind one of them", name, action, chip)
+			}
+		}
+	}
+
+	return nil
+}
+
+// HotkeyActions returns the actions a key can be bound to, sorted.
+func HotkeyActions() []string {
+	actions := make([]string, 0, len(DEFAULT_HOTKEYS))
+	for action := range DEFAULT_HOTKEYS {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+
+	return actions
+}
+
+// justPressed reports whether the key bound to an action went down this frame.
+func (h Hotkeys) justPressed(action string) bool {
+	key, ok := h[action]
+
+	return ok && inpututil.IsKeyJustPressed(key)
+}
+
+// held reports whether the key bound to an action is down.
+func (h Hotkeys) held(action string) bool {
+	key, ok := h[action]
+
+	return ok && ebiten.IsKeyPressed(key)
+}
+
+// handleHotkeys runs the actions whose keys went down this frame.
+func (r *Runtime) handleHotkeys() {
+	e := r.emulator
+
+	if r.hotkeys.justPressed("pause") {
+		e.SetPaused(!e.Paused())
+		r.autoPaused = false
+
+		r.logger.Info("Pause toggled", "paused", e.Paused())
+		r.Notify("%s", onOff("Paused", e.Paused()))
+	}
+
+	if r.hotkeys.justPressed("step") && e.Paused() {
+		e.Step()
+	}
+
+	if r.hotkeys.justPressed("speed-down") {
+		e.SpeedDown()
+
+		r.logger.Info("Speed changed", "speed", e.Speed())
+		r.Notify("Speed %gx", e.Speed())
+	}
+
+	if r.hotkeys.justPressed("speed-up") {
+		e.SpeedUp()
+
+		r.logger.Info("Speed changed", "speed", e.Speed())
+		r.Notify("Speed %gx", e.Speed())
+	}
+
+	if r.hotkeys.justPressed("slow-motion") {
+		e.SetSlowMotion(!e.SlowMotion())
+
+		r.logger.Info("Slow motion toggled", "on", e.SlowMotion(), "speed", e.EffectiveSpeed())
+		r.Notify("%s", onOff("Slow motion", e.SlowMotion()))
+	}
+
+	// the beeper is silenced while fast forwarding, it would only stutter
+	if ff := r.hotkeys.held("fast-forward"); ff != e.FastForward() {
+		e.SetFastForward(ff)
+
+		if ff {
+			r.StopAudio()
+		}
+	}
+
+	if r.hotkeys.justPressed("reset") {
+		if err := e.Reset(); err != nil {
+			r.logger.Error("Could not reset", "err", err)
+			r.Notify("Could not reset: %v", err)
+		} else {
+			r.logger.Info("Machine reset")
+			r.Notify("Reset")
+		}
+	}
+
+	if r.hotkeys.justPressed("hard-reset") {
+		if err := e.Reload(); err != nil {
+			r.logger.Error("Could not reload ROM", "err", err)
+			r.Notify("Could not reload ROM: %v", err)
+		} else {
+			r.logger.Info("ROM reloaded", "path", e.Path())
+			r.Notify("ROM reloaded")
+		}
+	}
+
+	if r.hotkeys.justPressed("mute") {
+		r.SetMuted(!r.muted)
+
+		r.logger.Info("Mute toggled", "muted", r.muted)
+		r.Notify("%s", onOff("Mute", r.muted))
+	}
+
+	if r.hotkeys.justPressed("record") {
+		r.toggleRecording()
+	}
+
+	if r.hotkeys.justPressed("osd") {
+		r.osd.SetVisible(!r.osd.Visible())
+	}
+
+	if r.hotkeys.justPressed("fullscreen") {
+		ebiten.SetFullscreen(!ebiten.IsFullscreen())
+	}
+
+	if r.hotkeys.justPressed("screenshot") {
+		if path, err := r.screenshot(); err != nil {
+			r.logger.Error("Could not save screenshot", "err", err)
+			r.Notify("Could not save screenshot: %v", err)
+		} else {
+			r.logger.Info("Screenshot saved", "path", path)
+			r.Notify("Screenshot saved to %s", path)
+		}
+	}
+
+	// pause while the window is in the background, and resume when it comes
+	// back unless the player paused in the meantime
+	if r.pauseOnBlur {
+		focused := ebiten.IsFocused()
+
+		if !focused && !e.Paused() {
+			e.SetPaused(true)
+			r.autoPaused = true
+		} else if focused && r.autoPaused {
+			e.SetPaused(false)
+			r.autoPaused = false
+		}
+	}
+}
+
+// toggleRecording starts recording a clip into the capture directory, or
+// writes the one being recorded.
+func (r *Runtime) toggleRecording() {
+	if r.clip != nil {
+		if path, err := r.StopRecording(); err != nil {
+			r.logger.Error("Could not save clip", "err", err)
+			r.Notify("Could not save clip: %v", err)
+		} else {
+			r.logger.Info("Clip saved", "path", path)
+			r.Notify("Clip saved to %s", path)
+		}
+
+		return
+	}
+
+	path := filepath.Join(r.captureDir, capture.FileName(r.emulator.Path(), r.clipExt, time.Now()))
+
+	if err := r.StartRecording(path); err != nil {
+		r.logger.Error("Could not start recording", "err", err)
+		r.Notify("Could not start recording: %v", err)
+
+		return
+	}
+
+	r.Notify("Recording")
+}
+
+// onOff formats a notification about a toggle.
+func onOff(what string, on bool) string {
+	if on {
+		return what + " on"
+	}
+
+	return what + " off"
+}
+
+// screenshot saves the screen as a PNG, in the colors of the palette and
+// through the filter, next to the other captures or to stdout.
+func (r *Runtime) screenshot() (string, error) {
+	img := capture.Scale(r.image, r.shotScale)
+
+	if r.shotStdout {
+		return "stdout", capture.WritePNG(os.Stdout, img)
+	}
+
+	return capture.SavePNG(img, r.captureDir, r.emulator.Path())
+}
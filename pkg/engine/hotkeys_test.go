@@ -0,0 +1,80 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/otaviohenrique/zamorak/pkg/keymap"
+)
+
+func TestParseHotkeys(t *testing.T) {
+	h, err := ParseHotkeys("pause=P, reset=, screenshot=F5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h["pause"] != ebiten.KeyP || h["screenshot"] != ebiten.KeyF5 {
+		t.Errorf("pause, screenshot = %s, %s, want P, F5", h["pause"], h["screenshot"])
+	}
+
+	if _, ok := h["reset"]; ok {
+		t.Error("reset is still bound")
+	}
+
+	if h["step"] != ebiten.KeyF2 {
+		t.Errorf("step = %s, want the default F2", h["step"])
+	}
+}
+
+func TestParseHotkeysErrors(t *testing.T) {
+	tests := map[string]string{
+		"pause":          "want action=Key",
+		"jump=J":         "unknown hotkey action",
+		"pause=Nope":     "unknown key",
+		"pause=F2":       "bound to both hotkeys pause and step",
+		"mute=P,osd=P":   "bound to both hotkeys",
+		"pause=F2,step=": "",
+	}
+
+	for spec, want := range tests {
+		_, err := ParseHotkeys(spec)
+
+		if want == "" {
+			if err != nil {
+				t.Errorf("ParseHotkeys(%q) = %v", spec, err)
+			}
+
+			continue
+		}
+
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseHotkeys(%q) error = %v, want %q", spec, err, want)
+		}
+	}
+}
+
+func TestCheckKeymap(t *testing.T) {
+	k, err := keymap.Parse(keymap.DEFAULT_PRESET)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := ParseHotkeys("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.CheckKeymap(k); err != nil {
+		t.Errorf("default hotkeys clash with the default keymap: %v", err)
+	}
+
+	h, err = ParseHotkeys("pause=Q")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.CheckKeymap(k); err == nil || !strings.Contains(err.Error(), "hotkey pause and CHIP-8 key 4") {
+		t.Errorf("error = %v, want pause clashing with key 4", err)
+	}
+}
@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/otaviohenrique/zamorak/pkg/decoder"
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+)
+
+var (
+	// how long notifications stay on screen
+	NOTICE_DURATION = 3 * time.Second
+
+	// notifications shown at once, older ones are dropped
+	MAX_NOTICES = 4
+
+	// size of a character of the debug font, in screen pixels
+	osdCharWidth  = 6
+	osdLineHeight = 16
+
+	osdBackground = color.RGBA{A: 0xB0}
+)
+
+type notice struct {
+	text  string
+	until time.Time
+}
+
+// OSD is an overlay showing how fast the emulator runs and the state of the
+// machine, plus short notifications that fade away on their own. The stats
+// are only drawn while it is visible, notifications always are.
+type OSD struct {
+	visible bool
+	profile string
+	notices []notice
+
+	instructions int       // executed since the last measure
+	measured     time.Time // when the IPS were last measured
+	ips          float64
+}
+
+func NewOSD() *OSD {
+	o := new(OSD)
+
+	o.profile = decoder.PlatformCHIP8.String()
+	o.measured = time.Now()
+
+	return o
+}
+
+func (o *OSD) Visible() bool {
+	return o.visible
+}
+
+func (o *OSD) SetVisible(visible bool) {
+	o.visible = visible
+}
+
+// SetProfile sets the profile name shown in the stats.
+func (o *OSD) SetProfile(profile string) {
+	o.profile = profile
+}
+
+// Notify shows a message for NOTICE_DURATION.
+func (o *OSD) Notify(text string) {
+	if len(o.notices) == MAX_NOTICES {
+		o.notices = o.notices[1:]
+	}
+
+	o.notices = append(o.notices, notice{text: text, until: time.Now().Add(NOTICE_DURATION)})
+}
+
+// Hook counts executed instructions, it is installed on the emulator.
+func (o *OSD) Hook(c *interpreter.Chip8, pc uint16, instr decoder.Instruction) {
+	o.instructions++
+}
+
+// measure updates the instructions per second about once a second.
+func (o *OSD) measure(now time.Time) {
+	elapsed := now.Sub(o.measured)
+	if elapsed < time.Second {
+		return
+	}
+
+	o.ips = float64(o.instructions) / elapsed.Seconds()
+	o.instructions = 0
+	o.measured = now
+}
+
+// Draw draws the stats at the top of the screen and the notifications at the
+// bottom.
+func (o *OSD) Draw(screen *ebiten.Image, c *interpreter.Chip8, speed float64, pressed [16]bool) {
+	now := time.Now()
+	o.measure(now)
+
+	if o.visible && c != nil {
+		o.drawLines(screen, 0, o.stats(c, speed, pressed))
+	}
+
+	live := o.notices[:0]
+	for _, n := range o.notices {
+		if n.until.After(now) {
+			live = append(live, n)
+		}
+	}
+	o.notices = live
+
+	if len(o.notices) > 0 {
+		lines := make([]string, len(o.notices))
+		for i, n := range o.notices {
+			lines[i] = n.text
+		}
+
+		o.drawLines(screen, screen.Bounds().Dy()-len(lines)*osdLineHeight, lines)
+	}
+}
+
+// stats returns the lines of the stats overlay.
+func (o *OSD) stats(c *interpreter.Chip8, speed float64, pressed [16]bool) []string {
+	s := c.State()
+
+	var registers [2]strings.Builder
+	for i, v := range s.Registers {
+		fmt.Fprintf(&registers[i/8], "V%X %02X  ", i, v)
+	}
+
+	stack := "empty"
+	if s.SP >= 0 {
+		frames := make([]string, 0, s.SP+1)
+		for _, addr := range s.Stack[:s.SP+1] {
+			frames = append(frames, fmt.Sprintf("%03X", addr))
+		}
+
+		stack = strings.Join(frames, " ")
+	}
+
+	var keys []string
+	for key, down := range pressed {
+		if down {
+			keys = append(keys, fmt.Sprintf("%X", key))
+		}
+	}
+
+	if len(keys) == 0 {
+		keys = append(keys, "none")
+	}
+
+	return []string{
+		fmt.Sprintf("FPS %.0f  IPS %.0f  %s  %gx", ebiten.ActualFPS(), o.ips, o.profile, speed),
+		fmt.Sprintf("PC %03X  I %03X  DT %02X  ST %02X", s.PC, s.I, s.DelayTimer, s.SoundTimer),
+		strings.TrimSpace(registers[0].String()),
+		strings.TrimSpace(registers[1].String()),
+		"Stack " + stack,
+		"Keys " + strings.Join(keys, " "),
+	}
+}
+
+// drawLines prints lines from y down, on a dark box so they can be read over
+// the game.
+func (o *OSD) drawLines(screen *ebiten.Image, y int, lines []string) {
+	width := 0
+	for _, line := range lines {
+		width = max(width, len(line)*osdCharWidth)
+	}
+
+	vector.DrawFilledRect(screen, 0, float32(y), float32(width+8), float32(len(lines)*osdLineHeight), osdBackground, false)
+
+	for i, line := range lines {
+		ebitenutil.DebugPrintAt(screen, line, 4, y+i*osdLineHeight)
+	}
+}
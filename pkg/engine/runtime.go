@@ -2,45 +2,97 @@ package engine
 
 import (
 	"bytes"
+	"fmt"
 	"image"
-	"image/color"
 	"log/slog"
+	"math"
 	"os"
+	"strings"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/hajimehoshi/ebiten/v2/audio/wav"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/otaviohenrique/zamorak/pkg/capture"
+	"github.com/otaviohenrique/zamorak/pkg/display"
+	"github.com/otaviohenrique/zamorak/pkg/emulator"
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+	"github.com/otaviohenrique/zamorak/pkg/keymap"
+	"github.com/otaviohenrique/zamorak/pkg/keypad"
+	"github.com/otaviohenrique/zamorak/pkg/logger"
+	"github.com/otaviohenrique/zamorak/pkg/palette"
 )
 
 var (
-	colorWhite = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
-	colorBlack = color.RGBA{R: 0x0, G: 0x0, B: 0x0, A: 0xFF}
+	// the game is drawn this many times larger by default, so the on-screen
+	// keypad and the OSD have room to be readable
+	SCREEN_SCALE = 10
+
+	// degrees the game can be turned clockwise, for games made for screens
+	// standing on their side
+	ROTATIONS = []int{0, 90, 180, 270}
 )
 
 type Runtime struct {
-	width   int
-	height  int
-	keys    []ebiten.Key
-	image   *image.RGBA
-	aPlayer *audio.Player
-	logger  *slog.Logger
-}
+	width    int
+	height   int
+	keypad   *keypad.Keypad
+	pixels   *display.Framebuffer // which pixels are lit
+	palette  palette.Palette
+	filter   *display.Filter // nil draws pixels as soon as they change
+	image    *image.RGBA     // the pixels in the colors of the palette
+	aPlayer  *audio.Player
+	keymap   [16][]ebiten.Key // host keys bound to every CHIP-8 key
+	touch    *TouchKeypad     // on-screen keypad, nil when off
+	frame    *ebiten.Image    // the CHIP-8 screen, drawn scale times larger
+	scale    int
+	osd      *OSD
+	shaders  *ShaderChain  // post-processing of the scaled up screen, nil when off
+	scaled   *ebiten.Image // the scaled up screen the shaders read
+	rotation int
+	logger   *slog.Logger
 
-func NewRuntime(width, height int, gameSound []byte, logger *slog.Logger) *Runtime {
-	initializedImage := ebiten.NewImage(64, 32)
-	initializedImage.Fill(color.RGBA{}) // initialize all pixels to black, 0 alpha.
+	emulator    *emulator.Emulator
+	hotkeys     Hotkeys
+	muted       bool
+	pauseOnBlur bool
+	autoPaused  bool          // paused because the window lost focus
+	captureDir  string        // where screenshots are saved
+	shotScale   int           // screenshots are this many times the screen resolution
+	shotStdout  bool          // screenshots are written to stdout instead of a file
+	clip        *capture.Clip // gameplay being recorded, nil when not recording
+	clipExt     string        // format of clips started by the hotkey
+	clipScale   int
+	clipSkip    int
+	streams     *capture.Streams // raw video and sound fed every frame, nil when off
+	beeping     bool             // the sound timer ran on the last tick, muted or not
+	title       string           // window title, followed by the emulator status
+	shownTitle  string
+}
 
+func NewRuntime(width, height int, gameSound []byte, log *slog.Logger) *Runtime {
 	r := new(Runtime)
 
 	r.width = width
 	r.height = height
-	r.image = image.NewRGBA(image.Rect(0, 0, 64, 32))
+	r.pixels = display.NewFramebuffer(width, height)
+	r.palette = palette.PRESETS[palette.DEFAULT_PRESET]
+	r.image = image.NewRGBA(image.Rect(0, 0, width, height))
+	r.logger = log
+	r.keypad = keypad.New()
+	r.frame = ebiten.NewImage(width, height)
+	r.osd = NewOSD()
+	r.captureDir = "."
+	r.shotScale = 1
+	r.clipExt = ".gif"
+	r.clipScale = 1
+	r.clipSkip = 1
+
+	audioLog := logger.For(log, logger.AUDIO)
 
 	decodedSong, err := wav.DecodeWithoutResampling(bytes.NewReader(gameSound))
 
 	if err != nil {
-		logger.Error("ERROR DECODING SOUND FILE", "err", err, "bytes", gameSound)
+		audioLog.Error("ERROR DECODING SOUND FILE", "err", err, "size", len(gameSound))
 
 		os.Exit(1)
 	}
@@ -48,25 +100,186 @@ func NewRuntime(width, height int, gameSound []byte, logger *slog.Logger) *Runti
 	audioContext := audio.NewContext(44_000)
 	audioPlayer, err := audioContext.NewPlayer(decodedSong)
 	if err != nil {
-		logger.Error("ERROR CREATING AUDIO PLAYER")
+		audioLog.Error("ERROR CREATING AUDIO PLAYER", "err", err)
 
 		os.Exit(1)
 	}
 
 	r.aPlayer = audioPlayer
+	r.scale = SCREEN_SCALE
 
 	r.ClearScreen()
 
+	if err := r.SetKeymap(keymap.PRESETS[keymap.DEFAULT_PRESET]); err != nil {
+		log.Error("Invalid default keymap", "err", err)
+	}
+
 	return r
 }
 
+// Attach makes the window drive an emulator, one Update per frame, and
+// handle its hotkeys.
+func (r *Runtime) Attach(e *emulator.Emulator, hotkeys Hotkeys) {
+	r.emulator = e
+	r.hotkeys = hotkeys
+
+	e.AddHook(r.osd.Hook)
+	e.OnFrame(r.vblank)
+	e.OnReset(r.reset)
+}
+
+// OSD returns the overlay drawn on top of the game.
+func (r *Runtime) OSD() *OSD {
+	return r.osd
+}
+
+// Notify shows a message on screen for a few seconds.
+func (r *Runtime) Notify(format string, args ...any) {
+	r.osd.Notify(fmt.Sprintf(format, args...))
+}
+
+// SetTitle sets the window title. The speed and whether the emulator is
+// paused are shown after it.
+func (r *Runtime) SetTitle(title string) {
+	r.title = title
+	r.updateTitle()
+}
+
+// updateTitle shows the emulator status in the window title when it changed.
+func (r *Runtime) updateTitle() {
+	title := r.title
+
+	if e := r.emulator; e != nil {
+		var status []string
+
+		switch {
+		case e.Stopped() != nil:
+			status = append(status, "stopped")
+		case e.Paused():
+			status = append(status, "paused")
+		case e.FastForward():
+			status = append(status, fmt.Sprintf("fast forward %gx", e.EffectiveSpeed()))
+		case e.SlowMotion():
+			status = append(status, fmt.Sprintf("slow motion %gx", e.EffectiveSpeed()))
+		case e.Speed() != 1:
+			status = append(status, fmt.Sprintf("%gx", e.Speed()))
+		}
+
+		if r.muted {
+			status = append(status, "muted")
+		}
+
+		if r.clip != nil {
+			status = append(status, "recording")
+		}
+
+		if len(status) > 0 {
+			title += " [" + strings.Join(status, ", ") + "]"
+		}
+	}
+
+	if title != r.shownTitle {
+		ebiten.SetWindowTitle(title)
+		r.shownTitle = title
+	}
+}
+
+// SetPauseOnBlur pauses the emulator while the window does not have focus.
+func (r *Runtime) SetPauseOnBlur(pause bool) {
+	r.pauseOnBlur = pause
+}
+
+// SetCaptureDir sets the directory screenshots are saved to.
+func (r *Runtime) SetCaptureDir(dir string) {
+	r.captureDir = dir
+}
+
+// SetScreenshotScale makes screenshots scale times the screen resolution.
+func (r *Runtime) SetScreenshotScale(scale int) {
+	if scale > 0 {
+		r.shotScale = scale
+	}
+}
+
+// SetScreenshotStdout writes screenshots to stdout instead of the capture
+// directory, to pipe them to another program.
+func (r *Runtime) SetScreenshotStdout(stdout bool) {
+	r.shotStdout = stdout
+}
+
+// SetClipOptions sets how clips are recorded: their format by file
+// extension when started by the hotkey, their scale, and the number of frames
+// out of which one is kept.
+func (r *Runtime) SetClipOptions(ext string, scale int, skip int) {
+	r.clipExt = ext
+	r.clipScale = scale
+	r.clipSkip = skip
+}
+
+// StartRecording records every frame into a clip written to path, in the
+// current palette and through the filter.
+func (r *Runtime) StartRecording(path string) error {
+	clip, err := capture.NewClip(path, r.palette, r.clipScale, r.clipSkip)
+	if err != nil {
+		return err
+	}
+
+	r.clip = clip
+
+	return nil
+}
+
+// Recording reports whether a clip is being recorded.
+func (r *Runtime) Recording() bool {
+	return r.clip != nil
+}
+
+// StopRecording writes the clip being recorded and returns its path.
+func (r *Runtime) StopRecording() (string, error) {
+	clip := r.clip
+	if clip == nil {
+		return "", fmt.Errorf("not recording")
+	}
+
+	r.clip = nil
+
+	return clip.Path(), clip.Close()
+}
+
+// SetStreams feeds every emulated frame to raw video and sound streams.
+func (r *Runtime) SetStreams(s *capture.Streams) {
+	r.streams = s
+}
+
+// SetVolume sets the beeper volume, from 0 to 1.
+func (r *Runtime) SetVolume(volume float64) {
+	r.aPlayer.SetVolume(volume)
+}
+
+// SetMuted silences the beeper.
+func (r *Runtime) SetMuted(muted bool) {
+	r.muted = muted
+
+	if muted {
+		r.StopAudio()
+	}
+}
+
 func (r *Runtime) PlayAudio() {
+	r.beeping = true
+
+	if r.muted || (r.emulator != nil && r.emulator.FastForward()) {
+		return
+	}
+
 	if !r.aPlayer.IsPlaying() {
 		r.aPlayer.Play()
 	}
 }
 
 func (r *Runtime) StopAudio() {
+	r.beeping = false
+
 	if r.aPlayer.IsPlaying() {
 		r.aPlayer.Pause()
 		r.aPlayer.Rewind()
@@ -74,125 +287,268 @@ func (r *Runtime) StopAudio() {
 }
 
 func (r *Runtime) IsPixelSet(col int, row int) bool {
-
-	isSet := r.image.RGBAAt(col, row) == colorWhite
-
-	return isSet
+	return r.pixels.IsPixelSet(col, row)
 }
 
 func (r *Runtime) Set(col int, row int, on bool) {
-	if on {
-		r.image.Set(col, row, colorWhite)
-	} else {
-		r.image.Set(col, row, colorBlack)
+	r.pixels.Set(col, row, on)
+
+	if r.filter == nil {
+		r.paint(col, row)
 	}
 }
 
+// ClearScreen clears the pixels. The filter keeps its intensities, games
+// clearing and drawing again every frame are the ones it is there for.
 func (r *Runtime) ClearScreen() {
-	for x := 0; x < 64; x++ {
-		for y := 0; y < 32; y++ {
-			r.image.Set(x, y, color.Black)
+	r.pixels.ClearScreen()
+
+	if r.filter == nil {
+		r.repaint()
+	}
+}
+
+// reset forgets the frames the filter saw when the program starts over.
+func (r *Runtime) reset() {
+	if r.filter != nil {
+		r.filter.Reset()
+	}
+
+	r.repaint()
+}
+
+// SetFilter sets the filter the screen goes through, nil to draw pixels as
+// soon as they change.
+func (r *Runtime) SetFilter(f *display.Filter) {
+	r.filter = f
+	r.repaint()
+}
+
+// vblank runs at the end of every emulated frame: the filter takes the frame
+// and the screen is drawn again through it, and the frame is added to the
+// clip being recorded and to the streams.
+func (r *Runtime) vblank() {
+	if r.filter != nil {
+		r.filter.Latch(r.pixels)
+		r.repaint()
+	}
+
+	if r.clip != nil {
+		r.clip.Frame(r.pixels, r.filter)
+	}
+
+	if r.streams != nil {
+		if err := r.streams.Frame(r.image, r.beeping); err != nil {
+			r.logger.Error("Could not write streams, stopping them", "err", err)
+			r.Notify("Streams stopped: %v", err)
+
+			r.streams.Close()
+			r.streams = nil
+		}
+	}
+}
+
+// SetPalette changes the colors the screen is drawn with.
+func (r *Runtime) SetPalette(p palette.Palette) {
+	r.palette = p
+	r.repaint()
+}
+
+// paint colors a pixel of the image after the framebuffer, or after the
+// filter when there is one.
+func (r *Runtime) paint(col int, row int) {
+	if r.filter != nil {
+		r.image.SetRGBA(col, row, capture.Mix(r.palette.Background(), r.palette.Color(1), r.filter.Intensity(col, row)))
+
+		return
+	}
+
+	var planes byte
+	if r.pixels.IsPixelSet(col, row) {
+		planes = 1
+	}
+
+	r.image.SetRGBA(col, row, r.palette.Color(planes))
+}
+
+// repaint colors the whole image after the framebuffer.
+func (r *Runtime) repaint() {
+	for row := 0; row < r.height; row++ {
+		for col := 0; col < r.width; col++ {
+			r.paint(col, row)
 		}
 	}
 }
 
+// SetShaders runs the game screen through a chain of shaders before it is
+// drawn, nil or an empty chain turns them off.
+func (r *Runtime) SetShaders(s *ShaderChain) {
+	if s != nil && s.Empty() {
+		s = nil
+	}
+
+	r.shaders = s
+
+	if s != nil && r.scaled == nil {
+		r.scaled = ebiten.NewImage(r.width*r.scale, r.height*r.scale)
+	}
+}
+
+// SetScale draws the game scale times larger, in whole pixels, and lays the
+// window out at that size. The OSD and the on-screen keypad take more room
+// of the window at small scales.
+func (r *Runtime) SetScale(scale int) {
+	if scale <= 0 {
+		return
+	}
+
+	r.scale = scale
+
+	if r.scaled != nil {
+		r.scaled = ebiten.NewImage(r.width*scale, r.height*scale)
+	}
+}
+
+// SetRotation turns the game clockwise by one of ROTATIONS.
+func (r *Runtime) SetRotation(degrees int) error {
+	for _, d := range ROTATIONS {
+		if d == degrees {
+			r.rotation = degrees
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid rotation %d, want 0, 90, 180 or 270", degrees)
+}
+
+// SetTouchKeypad shows an on-screen keypad.
+func (r *Runtime) SetTouchKeypad(t *TouchKeypad) {
+	r.touch = t
+}
+
+// ScreenSize returns the size of the screen Layout asks for.
+func (r *Runtime) ScreenSize() (int, int) {
+	w, h := r.width*r.scale, r.height*r.scale
+	if r.rotation == 90 || r.rotation == 270 {
+		w, h = h, w
+	}
+
+	if r.touch == nil {
+		return w, h
+	}
+
+	return r.touch.Layout(w, h)
+}
+
+// Update reports the host keys bound to every CHIP-8 key, and the keys held
+// on the touch keypad, to the keypad, then runs the hotkeys and the frames of
+// the attached emulator and shows its status in the window title.
 func (r *Runtime) Update() error {
-	r.keys = inpututil.AppendPressedKeys(r.keys[:0])
+	var touched [16]bool
+	if r.touch != nil {
+		touched = r.touch.Pressed()
+	}
+
+	for chip, keys := range r.keymap {
+		pressed := touched[chip]
+		for _, k := range keys {
+			if ebiten.IsKeyPressed(k) {
+				pressed = true
+				break
+			}
+		}
+
+		r.keypad.Set(byte(chip), pressed)
+	}
+
+	if r.emulator != nil {
+		r.handleHotkeys()
+		r.emulator.Update()
+		r.updateTitle()
+	}
 
 	return nil
 }
 
 func (r *Runtime) Draw(screen *ebiten.Image) {
-	screen.WritePixels(r.image.Pix)
+	r.frame.WritePixels(r.image.Pix)
+
+	game, scale := r.frame, float64(r.scale)
+
+	if r.shaders != nil {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(scale, scale)
+		r.scaled.DrawImage(r.frame, op)
+
+		game, scale = r.shaders.Apply(r.scaled, r.scale), 1
+	}
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(scale, scale)
+	r.rotate(&op.GeoM)
+	screen.DrawImage(game, op)
+
+	pressed := r.keypad.Pressed()
+
+	if r.touch != nil {
+		r.touch.Draw(screen, pressed, r.keypad.Polled(POLL_HIGHLIGHT))
+	}
+
+	var c *interpreter.Chip8
+	speed := 1.0
+	if r.emulator != nil {
+		c = r.emulator.Chip8()
+		speed = r.emulator.EffectiveSpeed()
+	}
+
+	r.osd.Draw(screen, c, speed, pressed)
+}
+
+// rotate turns the scaled up game by the rotation, keeping it at the top
+// left corner.
+func (r *Runtime) rotate(g *ebiten.GeoM) {
+	w, h := float64(r.width*r.scale), float64(r.height*r.scale)
+
+	switch r.rotation {
+	case 90:
+		g.Rotate(math.Pi / 2)
+		g.Translate(h, 0)
+	case 180:
+		g.Rotate(math.Pi)
+		g.Translate(w, h)
+	case 270:
+		g.Rotate(3 * math.Pi / 2)
+		g.Translate(0, w)
+	}
 }
 
+// Layout asks for a fixed size screen, ebiten scales it to the window keeping
+// its aspect ratio and letterboxes the rest.
 func (r *Runtime) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
-	return r.width, r.height
-}
-
-func (g *Runtime) WaitKeyPress(c chan byte) {
-	for _, key := range g.keys {
-		out := keyToByte(key)
-		c <- out
-	}
-}
-
-func keyToByte(key ebiten.Key) byte {
-	var out byte
-	switch key {
-	case ebiten.KeyDigit1:
-		out = 0x0
-	case ebiten.KeyDigit2:
-		out = 0x1
-	case ebiten.KeyDigit3:
-		out = 0x2
-	case ebiten.KeyDigit4:
-		out = 0x3
-	case ebiten.KeyQ:
-		out = 0x4
-	case ebiten.KeyW:
-		out = 0x5
-	case ebiten.KeyE:
-		out = 0x6
-	case ebiten.KeyR:
-		out = 0x7
-	case ebiten.KeyA:
-		out = 0x8
-	case ebiten.KeyS:
-		out = 0x9
-	case ebiten.KeyD:
-		out = 0xA
-	case ebiten.KeyF:
-		out = 0xB
-	case ebiten.KeyZ:
-		out = 0xC
-	case ebiten.KeyX:
-		out = 0xD
-	case ebiten.KeyC:
-		out = 0xE
-	case ebiten.KeyV:
-		out = 0xF
-	}
-
-	return out
-}
-
-func ByteToKey(b byte) ebiten.Key {
-	var out ebiten.Key
-	switch b {
-	case 0x0:
-		return ebiten.KeyDigit1
-	case 0x1:
-		return ebiten.KeyDigit2
-	case 0x2:
-		return ebiten.KeyDigit3
-	case 0x3:
-		return ebiten.KeyDigit4
-	case 0x4:
-		return ebiten.KeyQ
-	case 0x5:
-		return ebiten.KeyW
-	case 0x6:
-		return ebiten.KeyE
-	case 0x7:
-		return ebiten.KeyR
-	case 0x8:
-		return ebiten.KeyA
-	case 0x9:
-		return ebiten.KeyS
-	case 0xA:
-		return ebiten.KeyD
-	case 0xB:
-		return ebiten.KeyF
-	case 0xC:
-		return ebiten.KeyZ
-	case 0xD:
-		return ebiten.KeyX
-	case 0xE:
-		return ebiten.KeyC
-	case 0xF:
-		return ebiten.KeyV
-	}
-
-	return out
+	return r.ScreenSize()
+}
+
+// SetKeymap binds the CHIP-8 keys to the host keys of a keymap.
+func (r *Runtime) SetKeymap(k keymap.Keymap) error {
+	var keys [16][]ebiten.Key
+
+	for chip, names := range k {
+		for _, name := range names {
+			var key ebiten.Key
+			if err := key.UnmarshalText([]byte(name)); err != nil {
+				return fmt.Errorf("keymap: unknown key %q for %X", name, chip)
+			}
+
+			keys[chip] = append(keys[chip], key)
+		}
+	}
+
+	r.keymap = keys
+
+	return nil
+}
+
+func (r *Runtime) Keypad() *keypad.Keypad {
+	return r.keypad
 }
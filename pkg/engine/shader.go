@@ -0,0 +1,213 @@
+package engine
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+//go:embed shaders/*.kage
+var builtinShaders embed.FS
+
+var (
+	// parameters of the built-in shaders, and their defaults
+	SHADER_PARAMS = map[string]map[string]float64{
+		"crt":       {"Curvature": 0.15, "Vignette": 0.4},
+		"scanlines": {"Strength": 0.4},
+		"lcd":       {"Strength": 0.5},
+		"bloom":     {"Strength": 0.6, "Radius": 6},
+		"ghosting":  {"Strength": 0.7},
+	}
+)
+
+// Shaders returns the names of the built-in shaders, sorted.
+func Shaders() []string {
+	names := make([]string, 0, len(SHADER_PARAMS))
+	for name := range SHADER_PARAMS {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// shaderPass is one shader of a chain. It draws into one of its two images
+// every frame, and reads the other one, its output the frame before, as
+// image 1.
+type shaderPass struct {
+	name     string
+	shader   *ebiten.Shader
+	uniforms map[string]any
+	images   [2]*ebiten.Image
+	current  int
+}
+
+// ShaderChain runs the game screen through Kage shaders, in order, before it
+// is drawn. Shaders use the pixels unit and get the game screen, or the
+// output of the shader before them, as image 0. Besides their parameters
+// they get the uniforms Time, in seconds, and Scale, the size of a CHIP-8
+// pixel in screen pixels.
+type ShaderChain struct {
+	passes []*shaderPass
+	start  time.Time
+}
+
+// ParseShaderChain reads a chain written as shaders separated by commas,
+// each a built-in name or a path to a .kage file, optionally followed by
+// parameters, ex: crt:curvature=0.2,scanlines or lcd,~/glow.kage:amount=2.
+// Parameters set the float uniform of the same name, starting with an upper
+// case letter.
+func ParseShaderChain(spec string) (*ShaderChain, error) {
+	s := new(ShaderChain)
+
+	s.start = time.Now()
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		pass, err := parseShaderPass(item)
+		if err != nil {
+			return nil, err
+		}
+
+		s.passes = append(s.passes, pass)
+	}
+
+	return s, nil
+}
+
+// parseShaderPass reads a single shader of a chain and compiles it.
+func parseShaderPass(item string) (*shaderPass, error) {
+	p := new(shaderPass)
+
+	// a file path may hold colons itself, parameters start after .kage
+	var params string
+	if i := strings.Index(item, ".kage"); i >= 0 {
+		p.name = item[:i+len(".kage")]
+		params = strings.TrimPrefix(item[i+len(".kage"):], ":")
+	} else {
+		p.name, params, _ = strings.Cut(item, ":")
+	}
+
+	var src []byte
+	p.uniforms = make(map[string]any)
+
+	if defaults, ok := SHADER_PARAMS[p.name]; ok {
+		b, err := builtinShaders.ReadFile(path.Join("shaders", p.name+".kage"))
+		if err != nil {
+			return nil, err
+		}
+
+		src = b
+
+		for name, value := range defaults {
+			p.uniforms[name] = float32(value)
+		}
+	} else if strings.HasSuffix(p.name, ".kage") {
+		b, err := os.ReadFile(expandHome(p.name))
+		if err != nil {
+			return nil, fmt.Errorf("could not read shader: %w", err)
+		}
+
+		src = b
+	} else {
+		return nil, fmt.Errorf("unknown shader %q, want one of %s or a .kage file", p.name, strings.Join(Shaders(), ", "))
+	}
+
+	for _, param := range strings.Split(params, ":") {
+		if param == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid parameter %q of shader %s, want name=value", param, p.name)
+		}
+
+		f, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q of %s for shader %s", value, name, p.name)
+		}
+
+		p.uniforms[uniformName(name)] = float32(f)
+	}
+
+	shader, err := ebiten.NewShader(src)
+	if err != nil {
+		return nil, fmt.Errorf("could not compile shader %s: %w", p.name, err)
+	}
+
+	p.shader = shader
+
+	return p, nil
+}
+
+// uniformName turns a parameter name into the name of an exported uniform.
+func uniformName(name string) string {
+	r := []rune(strings.TrimSpace(name))
+	if len(r) > 0 {
+		r[0] = unicode.ToUpper(r[0])
+	}
+
+	return string(r)
+}
+
+func expandHome(p string) string {
+	if home, err := os.UserHomeDir(); err == nil && strings.HasPrefix(p, "~/") {
+		return filepath.Join(home, p[2:])
+	}
+
+	return p
+}
+
+// Empty reports whether the chain has no shaders.
+func (s *ShaderChain) Empty() bool {
+	return len(s.passes) == 0
+}
+
+// Apply runs src, the game drawn scale times larger, through every shader
+// and returns the output of the last one, which is only valid until the next
+// call.
+func (s *ShaderChain) Apply(src *ebiten.Image, scale int) *ebiten.Image {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	input := src
+
+	for _, p := range s.passes {
+		for i, img := range p.images {
+			if img == nil || img.Bounds().Dx() != w || img.Bounds().Dy() != h {
+				p.images[i] = ebiten.NewImage(w, h)
+			}
+		}
+
+		previous := p.images[p.current]
+		p.current = 1 - p.current
+		out := p.images[p.current]
+
+		p.uniforms["Time"] = float32(time.Since(s.start).Seconds())
+		p.uniforms["Scale"] = float32(scale)
+
+		op := &ebiten.DrawRectShaderOptions{}
+		op.Images[0] = input
+		op.Images[1] = previous
+		op.Uniforms = p.uniforms
+
+		out.Clear()
+		out.DrawRectShader(w, h, p.shader, op)
+
+		input = out
+	}
+
+	return input
+}
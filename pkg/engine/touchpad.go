@@ -0,0 +1,180 @@
+package engine
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/otaviohenrique/zamorak/pkg/keymap"
+)
+
+var (
+	// where the on-screen keypad goes: nowhere, right of the game, below it
+	// or on top of it
+	TOUCH_PLACEMENTS = []string{"off", "side", "below", "over"}
+
+	// keys a game checked this recently are highlighted
+	POLL_HIGHLIGHT = 250 * time.Millisecond
+
+	// key orders of the on-screen keypad, row by row
+	TOUCH_LAYOUTS = map[string][16]byte{
+		"vip": keymap.VIP_LAYOUT,
+		"hex": {
+			0x0, 0x1, 0x2, 0x3,
+			0x4, 0x5, 0x6, 0x7,
+			0x8, 0x9, 0xA, 0xB,
+			0xC, 0xD, 0xE, 0xF,
+		},
+	}
+
+	touchKeyColor     = color.RGBA{R: 0x40, G: 0x40, B: 0x40, A: 0xFF}
+	touchPressedColor = color.RGBA{R: 0xC0, G: 0xC0, B: 0xC0, A: 0xFF}
+	touchPolledColor  = color.RGBA{R: 0xFF, G: 0xB0, B: 0x00, A: 0xFF}
+)
+
+// TouchKeypad is a 4x4 hex keypad drawn on screen and pressed with the mouse
+// or by touch, for machines without a keyboard.
+type TouchKeypad struct {
+	placement string
+	order     [16]byte
+	opacity   float64
+	area      image.Rectangle // where the keypad is drawn, in screen pixels
+	touches   []ebiten.TouchID
+}
+
+func NewTouchKeypad(placement string, layout string, opacity float64) (*TouchKeypad, error) {
+	t := new(TouchKeypad)
+
+	if !contains(TOUCH_PLACEMENTS, placement) {
+		return nil, fmt.Errorf("unknown keypad placement %q, want one of %s", placement, strings.Join(TOUCH_PLACEMENTS, ", "))
+	}
+
+	order, ok := TOUCH_LAYOUTS[layout]
+	if !ok {
+		return nil, fmt.Errorf("unknown keypad layout %q, want vip or hex", layout)
+	}
+
+	if opacity < 0 || opacity > 1 {
+		return nil, fmt.Errorf("keypad opacity must be between 0 and 1, got %v", opacity)
+	}
+
+	t.placement = placement
+	t.order = order
+	t.opacity = opacity
+
+	return t, nil
+}
+
+// Layout places the keypad around a game drawn in a gameW x gameH area at the
+// top left corner, and returns the size of the whole screen.
+func (t *TouchKeypad) Layout(gameW, gameH int) (int, int) {
+	switch t.placement {
+	case "side":
+		t.area = image.Rect(gameW, 0, gameW+gameH, gameH)
+
+		return gameW + gameH, gameH
+	case "below":
+		t.area = image.Rect(0, gameH, gameW, gameH+gameW/2)
+
+		return gameW, gameH + gameW/2
+	case "over":
+		t.area = image.Rect(0, 0, gameW, gameH)
+	default:
+		t.area = image.Rectangle{}
+	}
+
+	return gameW, gameH
+}
+
+// Pressed returns the keys held down by the mouse or by any finger.
+func (t *TouchKeypad) Pressed() [16]bool {
+	var pressed [16]bool
+
+	if t.area.Empty() {
+		return pressed
+	}
+
+	if ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		if key, ok := t.keyAt(ebiten.CursorPosition()); ok {
+			pressed[key] = true
+		}
+	}
+
+	t.touches = ebiten.AppendTouchIDs(t.touches[:0])
+	for _, id := range t.touches {
+		if key, ok := t.keyAt(ebiten.TouchPosition(id)); ok {
+			pressed[key] = true
+		}
+	}
+
+	return pressed
+}
+
+// Draw draws the keypad, lighting up the keys that are held and outlining the
+// ones the game is checking.
+func (t *TouchKeypad) Draw(screen *ebiten.Image, pressed [16]bool, polled [16]bool) {
+	if t.area.Empty() {
+		return
+	}
+
+	w := float32(t.area.Dx()) / 4
+	h := float32(t.area.Dy()) / 4
+
+	for i, key := range t.order {
+		x := float32(t.area.Min.X) + float32(i%4)*w
+		y := float32(t.area.Min.Y) + float32(i/4)*h
+
+		fill := touchKeyColor
+		if pressed[key] {
+			fill = touchPressedColor
+		}
+
+		vector.DrawFilledRect(screen, x+2, y+2, w-4, h-4, t.fade(fill), false)
+
+		if polled[key] {
+			vector.StrokeRect(screen, x+3, y+3, w-6, h-6, 3, t.fade(touchPolledColor), false)
+		}
+
+		ebitenutil.DebugPrintAt(screen, fmt.Sprintf("%X", key), int(x+w/2)-3, int(y+h/2)-8)
+	}
+}
+
+// keyAt returns the key under a point of the screen.
+func (t *TouchKeypad) keyAt(x, y int) (byte, bool) {
+	p := image.Pt(x, y)
+	if !p.In(t.area) {
+		return 0, false
+	}
+
+	col := (x - t.area.Min.X) * 4 / t.area.Dx()
+	row := (y - t.area.Min.Y) * 4 / t.area.Dy()
+
+	return t.order[row*4+col], true
+}
+
+// fade applies the keypad opacity to a color.
+func (t *TouchKeypad) fade(c color.RGBA) color.Color {
+	a := t.opacity
+
+	return color.RGBA{
+		R: uint8(float64(c.R) * a),
+		G: uint8(float64(c.G) * a),
+		B: uint8(float64(c.B) * a),
+		A: uint8(float64(c.A) * a),
+	}
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,104 @@
+package headless
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+)
+
+// Event presses or releases a key at the start of a frame.
+type Event struct {
+	Frame   int
+	Key     byte
+	Pressed bool
+}
+
+// Machine is an interpreter and a headless runtime stepped one frame at a
+// time, as fast as the host allows. Runs are deterministic for a given seed,
+// program and list of events.
+type Machine struct {
+	chip8   *interpreter.Chip8
+	runtime *Runtime
+	events  []Event
+	frame   int
+	ipf     int
+	err     error
+}
+
+func NewMachine(log *slog.Logger, quirks interpreter.Quirks, seed int64, programData []byte) (*Machine, error) {
+	m := new(Machine)
+
+	m.chip8 = interpreter.NewChip8(log, quirks)
+	m.chip8.Seed(seed)
+	m.runtime = NewRuntime()
+	m.ipf = interpreter.INSTRUCTIONS_PER_FRAME
+
+	if err := m.chip8.Load(programData); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *Machine) Chip8() *interpreter.Chip8 {
+	return m.chip8
+}
+
+func (m *Machine) Runtime() *Runtime {
+	return m.runtime
+}
+
+// Frame returns the number of frames run so far.
+func (m *Machine) Frame() int {
+	return m.frame
+}
+
+// Schedule queues key events, applied when their frame is reached.
+func (m *Machine) Schedule(events ...Event) {
+	m.events = append(m.events, events...)
+}
+
+// RunFrame runs one frame. A program that crashes the interpreter, for
+// example by returning from an empty stack, faults the machine: the error is
+// returned by this and every later call.
+func (m *Machine) RunFrame() (err error) {
+	if m.err != nil {
+		return m.err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			m.err = fmt.Errorf("fault at frame %d, pc 0x%03X: %v", m.frame, m.chip8.PC(), p)
+			err = m.err
+		}
+	}()
+
+	for _, e := range m.events {
+		if e.Frame != m.frame {
+			continue
+		}
+
+		if e.Pressed {
+			m.runtime.Press(e.Key)
+		} else {
+			m.runtime.Release(e.Key)
+		}
+	}
+
+	m.chip8.RunFrame(m.runtime, m.ipf)
+	m.frame++
+
+	return nil
+}
+
+// RunFrames runs n frames, stopping at the first fault.
+func (m *Machine) RunFrames(n int) error {
+	for i := 0; i < n; i++ {
+		if err := m.RunFrame(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
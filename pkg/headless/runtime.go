@@ -0,0 +1,65 @@
+package headless
+
+import (
+	"github.com/otaviohenrique/zamorak/pkg/display"
+	"github.com/otaviohenrique/zamorak/pkg/keypad"
+)
+
+// Runtime is an interpreter.Runtime without a window, audio device or
+// keyboard. Keys are pressed and released by the caller and the display is an
+// in-memory framebuffer, so any number of them can run side by side.
+type Runtime struct {
+	framebuffer *display.Framebuffer
+	keypad      *keypad.Keypad
+	beeping     bool
+}
+
+func NewRuntime() *Runtime {
+	r := new(Runtime)
+
+	r.framebuffer = display.NewFramebuffer(64, 32)
+	r.keypad = keypad.New()
+
+	return r
+}
+
+func (r *Runtime) Framebuffer() *display.Framebuffer {
+	return r.framebuffer
+}
+
+func (r *Runtime) IsPixelSet(col int, row int) bool {
+	return r.framebuffer.IsPixelSet(col, row)
+}
+
+func (r *Runtime) Set(col int, row int, on bool) {
+	r.framebuffer.Set(col, row, on)
+}
+
+func (r *Runtime) ClearScreen() {
+	r.framebuffer.ClearScreen()
+}
+
+func (r *Runtime) PlayAudio() {
+	r.beeping = true
+}
+
+func (r *Runtime) StopAudio() {
+	r.beeping = false
+}
+
+// IsBeeping reports whether the sound timer was running on the last tick.
+func (r *Runtime) IsBeeping() bool {
+	return r.beeping
+}
+
+func (r *Runtime) Keypad() *keypad.Keypad {
+	return r.keypad
+}
+
+func (r *Runtime) Press(key byte) {
+	r.keypad.Press(key)
+}
+
+func (r *Runtime) Release(key byte) {
+	r.keypad.Release(key)
+}
@@ -1,13 +1,16 @@
 package interpreter
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/rand"
 	"time"
 
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/otaviohenrique/zamorak/pkg/engine"
+	"github.com/otaviohenrique/zamorak/pkg/decoder"
+	"github.com/otaviohenrique/zamorak/pkg/keypad"
+	"github.com/otaviohenrique/zamorak/pkg/logger"
 )
 
 var (
@@ -23,7 +26,12 @@ var (
 	// so you can follow that convention if you want.
 	FONT_OFFSET = 0x50
 
-	TIMER_TICK = 000 / 60
+	// corresponds to about 700 instructions per second at 60 frames per second
+	INSTRUCTIONS_PER_FRAME = 12
+
+	// returned by Frame when the program jumps to itself, the usual way
+	// CHIP-8 programs end
+	ErrHalted = errors.New("program halted")
 
 	// set of fonts
 	FONT_SET = []uint8{
@@ -64,10 +72,25 @@ type Chip8 struct {
 	memory        [4096]byte //4kb internal memory
 	delayTimer    byte
 	soundTimer    byte
-	logger        *slog.Logger
+	quirks        Quirks
+	random        *rand.Rand
+	waitVBlank    bool   // set by DXYN when the display wait quirk ends the frame, and by FX0A while it waits
+	keyWaiting    bool   // FX0A is waiting for a key
+	keyWaited     int    // key FX0A saw going down, -1 until one does
+	halted        bool   // set by a jump to the instruction itself
+	instrPC       uint16 // address of the instruction being executed
+	hooks         []Hook
+	logger        *slog.Logger // cpu
+	displayLog    *slog.Logger
+	inputLog      *slog.Logger
+	audioLog      *slog.Logger
 }
 
-func NewChip8(log *slog.Logger) *Chip8 {
+// Hook is called before every instruction is executed, with the address it
+// was fetched from.
+type Hook func(c *Chip8, pc uint16, instr decoder.Instruction)
+
+func NewChip8(log *slog.Logger, quirks Quirks) *Chip8 {
 	c := new(Chip8)
 
 	c.stack = [32]uint16{}
@@ -78,43 +101,105 @@ func NewChip8(log *slog.Logger) *Chip8 {
 	c.memory = [4096]byte{}
 	c.delayTimer = 0x0
 	c.soundTimer = 0x0
-	c.logger = log
+	c.quirks = quirks
+	c.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	c.logger = logger.For(log, logger.CPU)
+	c.displayLog = logger.For(log, logger.DISPLAY)
+	c.inputLog = logger.For(log, logger.INPUT)
+	c.audioLog = logger.For(log, logger.AUDIO)
 
 	return c
 }
 
-func (c *Chip8) startDelayTimer() {
-	for {
-		time.Sleep(time.Millisecond * time.Duration(TIMER_TICK))
+// Seed makes CXNN produce the same sequence of random numbers on every run.
+func (c *Chip8) Seed(seed int64) {
+	c.random = rand.New(rand.NewSource(seed))
+}
 
-		if c.delayTimer > 0 {
-			c.delayTimer--
-		}
-	}
+// AddHook installs a function that observes every executed instruction.
+// Hooks run in the order they were added.
+func (c *Chip8) AddHook(h Hook) {
+	c.hooks = append(c.hooks, h)
 }
 
-func (c *Chip8) startSoundTimer(r *engine.Runtime) {
-	for {
-		time.Sleep(time.Millisecond * time.Duration(TIMER_TICK))
+// Registers returns a copy of V0 to VF.
+func (c *Chip8) Registers() [16]byte {
+	return c.registers
+}
 
-		if c.soundTimer > 0 {
-			c.soundTimer--
+// PC returns the address of the next instruction.
+func (c *Chip8) PC() uint16 {
+	return c.pc
+}
 
-			r.PlayAudio()
-		} else {
-			r.StopAudio()
-		}
+// Index returns the I register.
+func (c *Chip8) Index() uint16 {
+	return c.indexRegister
+}
+
+// StackDepth returns how many return addresses are on the stack.
+func (c *Chip8) StackDepth() int {
+	return c.stackFrame + 1
+}
+
+// Halted reports whether the program jumped to itself and can no longer make
+// progress.
+func (c *Chip8) Halted() bool {
+	return c.halted
+}
+
+// Timers returns the delay and sound timers.
+func (c *Chip8) Timers() (delay byte, sound byte) {
+	return c.delayTimer, c.soundTimer
+}
+
+// State is a copy of everything a program can observe.
+type State struct {
+	Registers  [16]byte
+	I          uint16
+	PC         uint16
+	SP         int // current stack frame, -1 when the stack is empty
+	Stack      [32]uint16
+	DelayTimer byte
+	SoundTimer byte
+	Memory     [4096]byte
+}
 
+// State returns a copy of the machine state.
+func (c *Chip8) State() State {
+	return State{
+		Registers:  c.registers,
+		I:          c.indexRegister,
+		PC:         c.pc,
+		SP:         c.stackFrame,
+		Stack:      c.stack,
+		DelayTimer: c.delayTimer,
+		SoundTimer: c.soundTimer,
+		Memory:     c.memory,
 	}
 }
 
-func (c *Chip8) Interpret(r *engine.Runtime, programData []byte) {
-	go c.startDelayTimer()
-	go c.startSoundTimer(r)
+// TickTimers decrements the delay and sound timers. It must be called 60 times
+// per second, the beeper sounds for as long as the sound timer is not zero.
+func (c *Chip8) TickTimers(r Runtime) {
+	if c.delayTimer > 0 {
+		c.delayTimer--
+	}
+
+	if c.soundTimer > 0 {
+		c.soundTimer--
+
+		r.PlayAudio()
+	} else {
+		r.StopAudio()
+	}
+}
 
+// Load copies the font set and the program into memory.
+func (c *Chip8) Load(programData []byte) error {
 	// Verifies if program size is greater than chip memory
-	if len := len(programData); len > CHIP_MEMORY {
-		c.logger.Error("Given program is larger than memory", "program_data", len, "chip_memory", CHIP_MEMORY)
+	if len := len(programData); len > CHIP_MEMORY-MEMORY_OFFSET {
+		return fmt.Errorf("given program is larger than memory: %d bytes, %d available", len, CHIP_MEMORY-MEMORY_OFFSET)
 	}
 
 	for i := range FONT_SET {
@@ -125,326 +210,470 @@ func (c *Chip8) Interpret(r *engine.Runtime, programData []byte) {
 		c.memory[MEMORY_OFFSET+i] = programData[i]
 	}
 
-	for {
-		// SET program counter to the first byte of the software
+	return nil
+}
+
+// RunFrame executes up to ipf instructions and ticks the timers once, which
+// is what happens in one 60 Hz frame. With the display wait quirk on, the
+// frame ends early after a sprite is drawn.
+func (c *Chip8) RunFrame(r Runtime, ipf int) {
+	c.waitVBlank = false
+
+	for i := 0; i < ipf && !c.waitVBlank; i++ {
+		c.Step(r)
+	}
+
+	c.TickTimers(r)
+}
+
+// Fault is returned by Frame when the program crashes the interpreter, for
+// example by returning from an empty stack.
+type Fault struct {
+	PC     uint16 // address of the faulting instruction
+	Opcode uint16
+	Reason any // value the interpreter panicked with
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("fault at pc 0x%03X (%04X): %v", f.PC, f.Opcode, f.Reason)
+}
+
+// Frame is RunFrame for frontends: it returns a *Fault instead of panicking
+// when the program crashes the interpreter, and ErrHalted once the program
+// jumped to itself, with the beeper stopped.
+func (c *Chip8) Frame(r Runtime, ipf int) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = &Fault{PC: c.instrPC, Opcode: c.opcodeAt(c.instrPC), Reason: p}
+		}
+	}()
+
+	c.RunFrame(r, ipf)
+
+	if c.halted {
+		r.StopAudio()
+
+		return ErrHalted
+	}
+
+	return nil
+}
+
+// waitKey implements FX0A the way the COSMAC VIP did: it waits for a key to
+// go down and then up again, and returns that key. It is called every time
+// FX0A runs and reports false until the key is released.
+func (c *Chip8) waitKey(k *keypad.Keypad) (byte, bool) {
+	if !c.keyWaiting {
+		c.keyWaiting = true
+		c.keyWaited = -1
+
+		// only edges from now on count
+		k.Events()
+	}
+
+	for _, e := range k.Events() {
+		if e.Pressed && c.keyWaited < 0 {
+			c.keyWaited = int(e.Key)
+		} else if !e.Pressed && int(e.Key) == c.keyWaited {
+			c.keyWaiting = false
 
-		b0 := c.memory[c.pc]
-		b1 := c.memory[c.pc+1]
-		c.pc += 2
+			return e.Key, true
+		}
+	}
 
-		// DECODE
+	// a key already held when FX0A started counts once it is released
+	if c.keyWaited < 0 {
+		for key, pressed := range k.Pressed() {
+			if pressed {
+				c.keyWaited = key
+				break
+			}
+		}
+	}
+
+	return 0, false
+}
 
-		instr := (b0 & 0xF0) >> 4 // first nibble, the instruction
-		X := b0 & 0x0F            // second nibble, register lookup!
+// hexValue formats a log attribute as hex only when the record is written, so
+// disabled debug logs do not pay for fmt.Sprintf on every instruction.
+type hexValue uint16
 
-		Y := (b1 & 0xF0) >> 4            // third nibble, register lookup!
-		N := b1 & 0x0F                   // fourth nibble, 4 bit number
-		NN := b1                         // NN = second byte
-		NNN := uint16(X)<<8 | uint16(NN) // NNN = second, third and fourth nibbles
+func (h hexValue) LogValue() slog.Value {
+	return slog.StringValue(fmt.Sprintf("%02x", uint16(h)))
+}
 
+// opcodeAt returns the two bytes at addr, or zero past the end of memory.
+func (c *Chip8) opcodeAt(addr uint16) uint16 {
+	if int(addr)+1 >= len(c.memory) {
+		return 0
+	}
+
+	return uint16(c.memory[addr])<<8 | uint16(c.memory[addr+1])
+}
+
+// Step fetches, decodes and executes a single instruction.
+func (c *Chip8) Step(r Runtime) {
+	// SET program counter to the first byte of the software
+
+	c.instrPC = c.pc
+	b0 := c.memory[c.pc]
+	b1 := c.memory[c.pc+1]
+	c.pc += 2
+
+	// DECODE
+
+	decoded := decoder.Decode(b0, b1)
+
+	for _, hook := range c.hooks {
+		hook(c, c.instrPC, decoded)
+	}
+
+	instr := decoded.Kind
+	X := decoded.X
+	Y := decoded.Y
+	N := decoded.N
+	NN := decoded.NN
+	NNN := decoded.NNN
+
+	if c.logger.Enabled(context.Background(), slog.LevelDebug) {
 		c.logger.Debug(
 			"Instruction decoded",
-			"instruction", fmt.Sprintf("%02x", instr),
-			"X", fmt.Sprintf("%02x", X),
-			"Y", fmt.Sprintf("%02x", Y),
-			"N", fmt.Sprintf("%02x", N),
-			"NN", fmt.Sprintf("%02x", NN),
-			"NNN", fmt.Sprintf("%02x", NNN),
+			"PC", hexValue(c.instrPC),
+			"mnemonic", decoded.Mnemonic(),
+			"instruction", hexValue(instr),
+			"X", hexValue(X),
+			"Y", hexValue(Y),
+			"N", hexValue(N),
+			"NN", hexValue(NN),
+			"NNN", hexValue(NNN),
 		)
+	}
 
-		switch instr {
-		case 0x00:
-			switch Y {
-			case 0x0E:
-				switch N {
-				case 0x0: // clear screen
-					r.ClearScreen()
-
-					c.logger.Debug("Clear Screen Instruction", "INSTR", fmt.Sprintf("%02x", instr))
-				case 0xE:
-					c.pc = c.stack[c.stackFrame]
-					c.stackFrame--
-
-					c.logger.Debug("Set stack pointer to the top Instruction")
-				default:
-					c.logger.Warn("Unknown instruction", "INSTR", fmt.Sprintf("%02x", instr), "Y", fmt.Sprintf("%02x", Y))
-				}
+	switch instr {
+	case 0x00:
+		switch Y {
+		case 0x0E:
+			switch N {
+			case 0x0: // clear screen
+				r.ClearScreen()
+
+				c.displayLog.Debug("Clear Screen Instruction", "INSTR", hexValue(instr))
+			case 0xE:
+				c.pc = c.stack[c.stackFrame]
+				c.stackFrame--
+
+				c.logger.Debug("Set stack pointer to the top Instruction")
+			default:
+				c.logger.Warn("Unknown instruction", "INSTR", hexValue(instr), "Y", hexValue(Y))
 			}
+		}
+	case 0x1:
+		c.halted = NNN == c.instrPC
+		c.pc = NNN
+
+		c.logger.Debug("Jump to NNN Instruction. Set Program counter", "NNN", hexValue(NNN), "INSTR", hexValue(instr))
+	case 0x2:
+		c.stackFrame++
+		c.stack[c.stackFrame] = c.pc
+		c.pc = NNN
+
+		c.logger.Debug("Increment stack pointer", "INSTR", hexValue(instr))
+		c.logger.Debug("CALL subroutine at NNN", "NNN", hexValue(NNN), "INSTR", hexValue(instr))
+		c.logger.Debug("Put PC at top of the stack", "PC", hexValue(NNN), "INSTR", hexValue(instr))
+		c.logger.Debug("Set PC to NNN", "PC", hexValue(c.pc), "NNN", hexValue(NNN), "INSTR", hexValue(instr))
+	case 0x3:
+		VX := c.registers[X]
+		c.logger.Debug("Skip next instruction if Vx = kk (NN)", "VX", hexValue(VX), "NN", hexValue(NN), "INSTR", hexValue(instr))
+
+		if VX == NN {
+			c.pc += 2
+			c.logger.Debug("Skiping next instruction, VX == KK", "VX", hexValue(VX), "NN", hexValue(NN), "INSTR", hexValue(instr))
+		}
+	case 0x4:
+		VX := c.registers[X]
+		c.logger.Debug("Skip next instruction if Vx != kk (NN)", "VX", hexValue(VX), "NN", hexValue(NN), "INSTR", hexValue(instr))
+
+		if VX != NN {
+			c.pc += 2
+			c.logger.Debug("Skiping next instruction, VX != KK", "VX", hexValue(VX), "NN", hexValue(NN), "INSTR", hexValue(instr))
+		}
+	case 0x5:
+		VX := c.registers[X]
+		VY := c.registers[Y]
+
+		c.logger.Debug("Skip next instruction if Vx = Vy", "VX", hexValue(VX), "VY", hexValue(VY), "INSTR", hexValue(instr))
+
+		if N == 0x0 && VX == VY {
+			c.logger.Debug("Skiping next instruction Vx != Vy", "VX", hexValue(VX), "VY", hexValue(VY), "INSTR", hexValue(instr))
+			c.pc += 2
+		}
+	case 0x6:
+		VX := c.registers[X]
+		c.logger.Debug("SET Vx = KK", "VX", hexValue(VX), "KK (NN)", hexValue(NN), "INSTR", hexValue(instr))
+
+		c.registers[X] = NN
+	case 0x7:
+		c.logger.Debug("Set Vx = Vx + KK", "VX", hexValue(c.registers[X]), "KK (NN)", hexValue(NN), "INSTR", hexValue(instr))
+
+		c.registers[X] = NN + c.registers[X]
+	case 0x8:
+		switch N {
+		case 0x0:
+			c.logger.Debug("Set Vx = Vy", "VX", hexValue(c.registers[X]), "VY", hexValue(c.registers[Y]), "INSTR", hexValue(instr))
+
+			c.registers[X] = c.registers[Y]
 		case 0x1:
-			c.pc = NNN
+			c.logger.Debug("Set Vx = Vx OR Vy", "VX", hexValue(c.registers[X]), "VY", hexValue(c.registers[Y]), "INSTR", hexValue(instr))
+			c.registers[X] = c.registers[X] | c.registers[Y]
 
-			c.logger.Debug("Jump to NNN Instruction. Set Program counter", "NNN", fmt.Sprintf("%02x", NNN), "INSTR", fmt.Sprintf("%02x", instr))
+			if c.quirks.VFReset {
+				c.registers[0xF] = 0x0
+			}
 		case 0x2:
-			c.stackFrame++
-			c.stack[c.stackFrame] = c.pc
-			c.pc = NNN
-
-			c.logger.Debug("Increment stack pointer", "INSTR", fmt.Sprintf("%02x", instr))
-			c.logger.Debug("CALL subroutine at NNN", "NNN", fmt.Sprintf("%02x", NNN), "INSTR", fmt.Sprintf("%02x", instr))
-			c.logger.Debug("Put PC at top of the stack", "PC", fmt.Sprintf("%02x", NNN), "INSTR", fmt.Sprintf("%02x", instr))
-			c.logger.Debug("Set PC to NNN", "PC", fmt.Sprintf("%02x", c.pc), "NNN", fmt.Sprintf("%02x", NNN), "INSTR", fmt.Sprintf("%02x", instr))
+			c.logger.Debug("Set Vx = Vx AND Vy", "VX", hexValue(c.registers[X]), "VY", hexValue(c.registers[Y]), "INSTR", hexValue(instr))
+
+			c.registers[X] = c.registers[X] & c.registers[Y]
+
+			if c.quirks.VFReset {
+				c.registers[0xF] = 0x0
+			}
 		case 0x3:
-			VX := c.registers[X]
-			c.logger.Debug("Skip next instruction if Vx = kk (NN)", "VX", fmt.Sprintf("%02x", VX), "NN", fmt.Sprintf("%02x", NN), "INSTR", fmt.Sprintf("%02x", instr))
+			c.logger.Debug("Set Vx = Vx XOR Vy", "VX", hexValue(c.registers[X]), "VY", hexValue(c.registers[Y]), "INSTR", hexValue(instr))
 
-			if VX == NN {
-				c.pc += 2
-				c.logger.Debug("Skiping next instruction, VX == KK", "VX", fmt.Sprintf("%02x", VX), "NN", fmt.Sprintf("%02x", NN), "INSTR", fmt.Sprintf("%02x", instr))
+			c.registers[X] = c.registers[X] ^ c.registers[Y]
+
+			if c.quirks.VFReset {
+				c.registers[0xF] = 0x0
 			}
 		case 0x4:
-			VX := c.registers[X]
-			c.logger.Debug("Skip next instruction if Vx != kk (NN)", "VX", fmt.Sprintf("%02x", VX), "NN", fmt.Sprintf("%02x", NN), "INSTR", fmt.Sprintf("%02x", instr))
+			c.logger.Debug("Set Vx = Vx + Vy, set VF = carry", "VX", hexValue(c.registers[X]), "VY", hexValue(c.registers[Y]), "VF", hexValue(c.registers[0xF]), "INSTR", hexValue(instr))
 
-			if VX != NN {
-				c.pc += 2
-				c.logger.Debug("Skiping next instruction, VX != KK", "VX", fmt.Sprintf("%02x", VX), "NN", fmt.Sprintf("%02x", NN), "INSTR", fmt.Sprintf("%02x", instr))
+			sum := uint16(c.registers[X]) + uint16(c.registers[Y])
+
+			c.registers[X] = byte(sum)
+
+			// VF is written last so the flag survives when X is F
+			if int(sum) > 255 {
+				c.registers[0xF] = 0x1
+			} else {
+				c.registers[0xF] = 0x0
 			}
 		case 0x5:
-			VX := c.registers[X]
-			VY := c.registers[Y]
+			c.logger.Debug("Set Vx = Vx - Vy, set VF = carry", "VX", hexValue(c.registers[X]), "VY", hexValue(c.registers[Y]), "VF", hexValue(c.registers[0xF]), "INSTR", hexValue(instr))
 
-			c.logger.Debug("Skip next instruction if Vx = Vy", "VX", fmt.Sprintf("%02x", VX), "VY", fmt.Sprintf("%02x", VY), "INSTR", fmt.Sprintf("%02x", instr))
+			noBorrow := c.registers[X] >= c.registers[Y]
 
-			if N == 0x0 && VX == VY {
-				c.logger.Debug("Skiping next instruction Vx != Vy", "VX", fmt.Sprintf("%02x", VX), "VY", fmt.Sprintf("%02x", VY), "INSTR", fmt.Sprintf("%02x", instr))
-				c.pc += 2
+			c.registers[X] = c.registers[X] - c.registers[Y]
+
+			if noBorrow {
+				c.registers[0xF] = 0x1
+			} else {
+				c.registers[0xF] = 0x0
 			}
 		case 0x6:
-			VX := c.registers[X]
-			c.logger.Debug("SET Vx = KK", "VX", fmt.Sprintf("%02x", VX), "KK (NN)", fmt.Sprintf("%02x", NN), "INSTR", fmt.Sprintf("%02x", instr))
+			c.logger.Debug("Set Vx = Vx SHR 1", "VX", hexValue(c.registers[X]), "VY", hexValue(c.registers[Y]), "INSTR", hexValue(instr))
 
-			c.registers[X] = NN
-		case 0x7:
-			c.logger.Debug("Set Vx = Vx + KK", "VX", fmt.Sprintf("%02x", c.registers[X]), "KK (NN)", fmt.Sprintf("%02x", NN), "INSTR", fmt.Sprintf("%02x", instr))
+			// The original interpreter shifts VY into VX, later ones shift VX in place
+			value := c.registers[Y]
+			if c.quirks.Shifting {
+				value = c.registers[X]
+			}
 
-			c.registers[X] = NN + c.registers[X]
-		case 0x8:
-			switch N {
-			case 0x0:
-				c.logger.Debug("Set Vx = Vy", "VX", fmt.Sprintf("%02x", c.registers[X]), "VY", fmt.Sprintf("%02x", c.registers[Y]), "INSTR", fmt.Sprintf("%02x", instr))
-
-				c.registers[X] = c.registers[Y]
-			case 0x1:
-				c.logger.Debug("Set Vx = Vx OR Vy", "VX", fmt.Sprintf("%02x", c.registers[X]), "VY", fmt.Sprintf("%02x", c.registers[Y]), "INSTR", fmt.Sprintf("%02x", instr))
-				c.registers[X] = c.registers[X] | c.registers[Y]
-			case 0x2:
-				c.logger.Debug("Set Vx = Vx AND Vy", "VX", fmt.Sprintf("%02x", c.registers[X]), "VY", fmt.Sprintf("%02x", c.registers[Y]), "INSTR", fmt.Sprintf("%02x", instr))
-
-				c.registers[X] = c.registers[X] & c.registers[Y]
-			case 0x3:
-				c.logger.Debug("Set Vx = Vx XOR Vy", "VX", fmt.Sprintf("%02x", c.registers[X]), "VY", fmt.Sprintf("%02x", c.registers[Y]), "INSTR", fmt.Sprintf("%02x", instr))
-
-				c.registers[X] = c.registers[X] ^ c.registers[Y]
-			case 0x4:
-				c.logger.Debug("Set Vx = Vx + Vy, set VF = carry", "VX", fmt.Sprintf("%02x", c.registers[X]), "VY", fmt.Sprintf("%02x", c.registers[Y]), "VF", fmt.Sprintf("%02x", c.registers[0xF]), "INSTR", fmt.Sprintf("%02x", instr))
-
-				sum := uint16(c.registers[X]) + uint16(c.registers[Y])
-
-				if int(sum) > 255 {
-					c.registers[0xF] = 0x1
-				} else {
-					c.registers[0xF] = 0x0
-				}
+			c.registers[X] = value >> 1
 
-				c.registers[X] = byte(sum)
-			case 0x5:
-				c.logger.Debug("Set Vx = Vx - Vy, set VF = carry", "VX", fmt.Sprintf("%02x", c.registers[X]), "VY", fmt.Sprintf("%02x", c.registers[Y]), "VF", fmt.Sprintf("%02x", c.registers[0xF]), "INSTR", fmt.Sprintf("%02x", instr))
+			// VF is written last so the flag survives when X is F
+			c.registers[0xF] = value & 0x01
+		case 0x7:
+			c.logger.Debug("Set Vx = Vy - Vx, set VF = NOT borrow", "VX", hexValue(c.registers[X]), "VY", hexValue(c.registers[Y]), "VF", hexValue(c.registers[0xF]), "INSTR", hexValue(instr))
 
-				if c.registers[X] > c.registers[Y] {
-					c.registers[0xF] = 0x1
-				} else {
-					c.registers[0xF] = 0x0
-				}
+			noBorrow := c.registers[Y] >= c.registers[X]
 
-				c.registers[X] = c.registers[X] - c.registers[Y]
-			case 0x6:
-				c.logger.Debug("Set Vx = Vx SHR 1", "VX", fmt.Sprintf("%02x", c.registers[X]), "INSTR", fmt.Sprintf("%02x", instr))
-				lastBit := c.registers[X] & 0x01
+			result := c.registers[Y] - c.registers[X]
 
-				if lastBit > 0 {
-					c.registers[0xF] = 0x1
-				} else {
-					c.registers[0xF] = 0x0
-				}
-				//
-				//In Go, the right shift operator (>>) is often used to perform division by powers of 2 for integers. Shifting a binary number to the right by one position is equivalent to dividing it by 2.
-				//
-				//Here's a simple explanation:
-				//
-				//Shifting a binary number to the right by 1 is the same as dividing it by 2.
-				//Shifting a binary number to the right by 2 is the same as dividing it by 4.
-				//Shifting a binary number to the right by n is the same as dividing it by 2^n.
-				//In the context of your CHIP-8 emulator, registers[X] >>= 1 is a concise way of expressing "divide registers[X] by 2." It's a common idiom used in low-level programming, especially when dealing with bitwise operations and binary representations of numbers.
-				//
-				//For example, if registers[X] is a binary number like 11010010, then registers[X] >>= 1 would result in 01101001, which is the value of registers[X] divided by 2.
-				//
-				//Using >> for division by powers of 2 is efficient and works well when you're dealing with integers and you want to express division in terms of binary operations.
-				//
-				c.registers[X] = c.registers[X] >> 1
-			case 0x7:
-				c.logger.Debug("Set Vx = Vy - Vx, set VF = NOT borrow", "VX", fmt.Sprintf("%02x", c.registers[X]), "VY", fmt.Sprintf("%02x", c.registers[Y]), "VF", fmt.Sprintf("%02x", c.registers[0xF]), "INSTR", fmt.Sprintf("%02x", instr))
-
-				if c.registers[Y] > c.registers[X] {
-					c.registers[0xF] = 0x1
-				} else {
-					c.registers[0xF] = 0x0
-				}
+			c.registers[X] = result
 
-				result := c.registers[X] - c.registers[Y]
+			if noBorrow {
+				c.registers[0xF] = 0x1
+			} else {
+				c.registers[0xF] = 0x0
+			}
+		case 0xE:
+			c.logger.Debug("Set Vx = Vx SHL 1", "VX", hexValue(c.registers[X]), "VY", hexValue(c.registers[Y]), "INSTR", hexValue(instr))
 
-				c.registers[X] = result
-			case 0xE:
-				c.logger.Debug("Set Vx = Vx SHL 1", "VX", fmt.Sprintf("%02x", c.registers[X]), "INSTR", fmt.Sprintf("%02x", instr))
+			value := c.registers[Y]
+			if c.quirks.Shifting {
+				value = c.registers[X]
+			}
 
-				c.registers[X] = c.registers[Y]
+			c.registers[X] = value << 1
 
-				// check if leftmost bit is set (and shifted out)
-				if c.registers[X]&(1<<7) > 0 {
-					c.registers[0xF] = 0x1
-				} else {
-					c.registers[0xF] = 0x0
-				}
-				c.registers[X] = c.registers[X] << 1
-			}
-		case 0x9:
-			c.logger.Debug("Skip next instruction if Vx != Vy", "VX", fmt.Sprintf("%02x", c.registers[X]), "VY", fmt.Sprintf("%02x", c.registers[Y]), "INSTR", fmt.Sprintf("%02x", instr))
+			// check if leftmost bit is set (and shifted out)
+			c.registers[0xF] = value >> 7
+		}
+	case 0x9:
+		c.logger.Debug("Skip next instruction if Vx != Vy", "VX", hexValue(c.registers[X]), "VY", hexValue(c.registers[Y]), "INSTR", hexValue(instr))
 
-			if c.registers[X] != c.registers[Y] {
-				c.pc += 2 // SKIP INSTRUCTION (wrap on function)
-			}
-		case 0xA:
-			c.logger.Debug("Set I = nnn", "I", fmt.Sprintf("%02x", c.indexRegister), "NNN", fmt.Sprintf("%02x", NNN), "INSTR", fmt.Sprintf("%02x", instr))
+		if c.registers[X] != c.registers[Y] {
+			c.pc += 2 // SKIP INSTRUCTION (wrap on function)
+		}
+	case 0xA:
+		c.logger.Debug("Set I = nnn", "I", hexValue(c.indexRegister), "NNN", hexValue(NNN), "INSTR", hexValue(instr))
 
-			c.indexRegister = NNN
-		case 0xB:
-			c.logger.Debug("Jump to location nnn + V0", "V0", fmt.Sprintf("%02x", c.registers[0x0]), "NNN", fmt.Sprintf("%02x", NNN), "INSTR", fmt.Sprintf("%02x", instr))
+		c.indexRegister = NNN
+	case 0xB:
+		if c.quirks.Jumping {
+			c.logger.Debug("Jump to location xnn + Vx", "VX", hexValue(c.registers[X]), "NNN", hexValue(NNN), "INSTR", hexValue(instr))
+
+			c.pc = NNN + uint16(c.registers[X])
+		} else {
+			c.logger.Debug("Jump to location nnn + V0", "V0", hexValue(c.registers[0x0]), "NNN", hexValue(NNN), "INSTR", hexValue(instr))
 
 			c.pc = NNN + uint16(c.registers[0x0])
-		case 0xC:
-			rand := rand.Intn(256)
+		}
+	case 0xC:
+		rand := c.random.Intn(256)
 
-			c.registers[X] = byte(rand) & NN
+		c.registers[X] = byte(rand) & NN
 
-			c.logger.Debug("Set Vx = random byte AND kk", "RANDOM BYTE", fmt.Sprintf("%02x", rand), "VX", fmt.Sprintf("%02x", c.registers[X]), "KK (NN)", fmt.Sprintf("%02x", NN), "INSTR", fmt.Sprintf("%02x", instr))
-		case 0xD:
-			xc := c.registers[X] % 64
-			yc := c.registers[Y] % 32
+		c.logger.Debug("Set Vx = random byte AND kk", "RANDOM BYTE", hexValue(rand), "VX", hexValue(c.registers[X]), "KK (NN)", hexValue(NN), "INSTR", hexValue(instr))
+	case 0xD:
+		c.displayLog.Debug("Draw sprite at (Vx, Vy) from I", "VX", hexValue(c.registers[X]), "VY", hexValue(c.registers[Y]), "N", hexValue(N), "I", hexValue(c.indexRegister), "INSTR", hexValue(instr))
 
-			c.registers[0xF] = 0x0
+		xc := c.registers[X] % 64
+		yc := c.registers[Y] % 32
 
-			numLines := int(N)
-			firstByteIndex := c.indexRegister
+		c.registers[0xF] = 0x0
 
-			for line := 0; line < numLines; line++ {
-				// first byte index
-				sprite := c.memory[firstByteIndex]
+		numLines := int(N)
+		firstByteIndex := c.indexRegister
 
-				row := int(yc) + line
-				if row > 31 {
+		for line := 0; line < numLines; line++ {
+			// first byte index
+			sprite := c.memory[firstByteIndex]
+			firstByteIndex++
+
+			row := int(yc) + line
+			if row > 31 {
+				if c.quirks.Clipping {
 					continue
 				}
 
-				for bit := 0; bit < 8; bit++ {
+				row = row % 32
+			}
 
-					col := int(xc) + bit
-					// ignore if outside of screen
-					if col > 63 {
+			for bit := 0; bit < 8; bit++ {
+
+				col := int(xc) + bit
+				// ignore if outside of screen, or wrap around to the other side
+				if col > 63 {
+					if c.quirks.Clipping {
 						continue
 					}
 
-					// check if bit is set, moving from left-most bit to the right
-					if sprite&(1<<(7-bit)) > 0 {
-						if r.IsPixelSet(col, row) {
-							r.Set(col, row, false)
-							// set register F to 1
-							c.registers[0xF] = 0x1
-						} else {
-							r.Set(col, row, true)
-						}
+					col = col % 64
+				}
+
+				// check if bit is set, moving from left-most bit to the right
+				if sprite&(1<<(7-bit)) > 0 {
+					if r.IsPixelSet(col, row) {
+						r.Set(col, row, false)
+						// set register F to 1
+						c.registers[0xF] = 0x1
+					} else {
+						r.Set(col, row, true)
 					}
 				}
-				firstByteIndex++
 			}
-		case 0xE:
-			switch NN {
-			case 0x9E:
-				fmt.Printf("Skip next instruction if key with the value of Vx is pressed.\n")
+		}
 
-				if ebiten.IsKeyPressed(engine.ByteToKey(c.registers[X])) {
-					c.pc += 2
-				}
-			case 0xA1:
-				fmt.Printf("Skip next instruction if key with the value of Vx is not pressed.\n")
+		if c.quirks.DisplayWait {
+			c.waitVBlank = true
+		}
+	case 0xE:
+		switch NN {
+		case 0x9E:
+			c.inputLog.Debug("Skip next instruction if key with the value of Vx is pressed", "VX", hexValue(c.registers[X]), "INSTR", hexValue(instr))
 
-				if !ebiten.IsKeyPressed(engine.ByteToKey(c.registers[X])) {
-					c.pc += 2
-				}
-			default:
-				fmt.Printf("Unknown Instruction\n")
+			if r.Keypad().Poll(c.registers[X]) {
+				c.pc += 2
 			}
-		case 0xF:
-			fmt.Printf("Timer instruction.\n")
-
-			switch NN {
-			case 0x07:
-				fmt.Printf("Set Vx = delayTimer\n")
+		case 0xA1:
+			c.inputLog.Debug("Skip next instruction if key with the value of Vx is not pressed", "VX", hexValue(c.registers[X]), "INSTR", hexValue(instr))
 
-				c.registers[X] = c.delayTimer
-			case 0x0A:
-				ch := make(chan byte)
-				go r.WaitKeyPress(ch)
+			if !r.Keypad().Poll(c.registers[X]) {
+				c.pc += 2
+			}
+		default:
+			c.logger.Warn("Unknown instruction", "INSTR", hexValue(instr), "NN", hexValue(NN))
+		}
+	case 0xF:
+		switch NN {
+		case 0x07:
+			c.logger.Debug("Set Vx = delayTimer", "VX", hexValue(c.registers[X]), "DT", hexValue(c.delayTimer), "INSTR", hexValue(instr))
 
-				key := <-ch
+			c.registers[X] = c.delayTimer
+		case 0x0A:
+			c.inputLog.Debug("Wait for a key press, store the value of the key in Vx", "X", hexValue(X), "INSTR", hexValue(instr))
 
+			if key, ok := c.waitKey(r.Keypad()); ok {
 				c.registers[X] = key
-			case 0x15:
-				fmt.Printf("Set delayTimer = Vx\n")
+			} else {
+				// run FX0A again next frame, timers keep ticking meanwhile
+				c.pc -= 2
+				c.waitVBlank = true
+			}
+		case 0x15:
+			c.logger.Debug("Set delayTimer = Vx", "VX", hexValue(c.registers[X]), "INSTR", hexValue(instr))
 
-				c.delayTimer = c.registers[X]
-			case 0x18:
-				fmt.Printf("Set soundTimer = Vx\n")
+			c.delayTimer = c.registers[X]
+		case 0x18:
+			c.audioLog.Debug("Set soundTimer = Vx", "VX", hexValue(c.registers[X]), "INSTR", hexValue(instr))
 
-				c.soundTimer = c.registers[X]
-			case 0x1E:
-				fmt.Printf("Set I = I + Vx\n")
+			c.soundTimer = c.registers[X]
+		case 0x1E:
+			c.logger.Debug("Set I = I + Vx", "I", hexValue(c.indexRegister), "VX", hexValue(c.registers[X]), "INSTR", hexValue(instr))
 
-				c.indexRegister = c.indexRegister + uint16(c.registers[X])
-			case 0x29:
-				fmt.Printf("Set I = location of sprite for digit Vx.\n")
+			c.indexRegister = c.indexRegister + uint16(c.registers[X])
+		case 0x29:
+			c.logger.Debug("Set I = location of sprite for digit Vx", "VX", hexValue(c.registers[X]), "INSTR", hexValue(instr))
 
-				b := c.registers[X] & 0x0F
+			// every digit is 5 bytes long
+			b := c.registers[X] & 0x0F
 
-				c.indexRegister = uint16(FONT_SET[b])
-			case 0x33:
-				fmt.Printf("Store BCD representation of Vx in memory locations I, I+1, and I+2.\n")
+			c.indexRegister = uint16(FONT_OFFSET) + uint16(b)*5
+		case 0x33:
+			c.logger.Debug("Store BCD representation of Vx in memory locations I, I+1, and I+2", "VX", hexValue(c.registers[X]), "I", hexValue(c.indexRegister), "INSTR", hexValue(instr))
 
-				//The interpreter takes the decimal value of Vx,``
-				//and places the hundreds digit in memory at location in I,
-				//the tens digit at location I+1, and the ones digit at location I+2.
+			//The interpreter takes the decimal value of Vx,``
+			//and places the hundreds digit in memory at location in I,
+			//the tens digit at location I+1, and the ones digit at location I+2.
 
-				c.memory[c.indexRegister+0] = (c.registers[X] / 100) % 10
-				c.memory[c.indexRegister+1] = (c.registers[X] / 10) % 10
-				c.memory[c.indexRegister+2] = (c.registers[X] / 1) % 10
-			case 0x55:
-				fmt.Printf("Store registers V0 through Vx in memory starting at location I.\n")
+			c.memory[c.indexRegister+0] = (c.registers[X] / 100) % 10
+			c.memory[c.indexRegister+1] = (c.registers[X] / 10) % 10
+			c.memory[c.indexRegister+2] = (c.registers[X] / 1) % 10
+		case 0x55:
+			c.logger.Debug("Store registers V0 through Vx in memory starting at location I", "X", hexValue(X), "I", hexValue(c.indexRegister), "INSTR", hexValue(instr))
 
-				for i := 0; i <= int(X); i++ {
-					index := c.indexRegister + uint16(i)
-					c.memory[index] = c.registers[i]
-				}
+			for i := 0; i <= int(X); i++ {
+				index := c.indexRegister + uint16(i)
+				c.memory[index] = c.registers[i]
+			}
+			if c.quirks.Memory {
 				c.indexRegister = c.indexRegister + uint16(X+1)
-			case 0x65:
-				fmt.Printf("Read registers V0 through Vx from memory starting at location I.")
+			}
+		case 0x65:
+			c.logger.Debug("Read registers V0 through Vx from memory starting at location I", "X", hexValue(X), "I", hexValue(c.indexRegister), "INSTR", hexValue(instr))
 
-				for i := uint8(0); i <= X; i++ {
-					c.registers[i] = c.memory[c.indexRegister]
-					c.indexRegister = c.indexRegister + 1
-				}
+			for i := 0; i <= int(X); i++ {
+				index := c.indexRegister + uint16(i)
+				c.registers[i] = c.memory[index]
 			}
-		default:
-			c.logger.Warn("Unknown instruction", "INSTR", fmt.Sprintf("%02x", instr), "Y", fmt.Sprintf("%02x", Y))
-		}
 
-		time.Sleep(time.Microsecond * 1300) // corresponds to about 700 instructions per second...
+			if c.quirks.Memory {
+				c.indexRegister = c.indexRegister + uint16(X+1)
+			}
+		}
+	default:
+		c.logger.Warn("Unknown instruction", "INSTR", hexValue(instr), "Y", hexValue(Y))
 	}
 }
@@ -0,0 +1,123 @@
+package interpreter_test
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/otaviohenrique/zamorak/pkg/headless"
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+)
+
+// starts the delay timer, waits for a key, then checks it with EX9E and EXA1
+// once per frame, the draw ending the frame with the display wait quirk
+var waitProgram = []byte{
+	0x61, 0x3C, // 613C: V1 = 60
+	0xF1, 0x15, // F115: DT = V1
+	0xF2, 0x0A, // F20A: V2 = key
+	0xF3, 0x07, // F307: V3 = DT
+	0x64, 0x01, // 6401: V4 = 1, the wait is over
+	0x65, 0x00, // 6500: V5 = 0
+	0xE2, 0x9E, // E29E: skip when the key in V2 is down
+	0x12, 0x12, // 1212
+	0x65, 0x01, // 6501: V5 = 1, down for EX9E
+	0x66, 0x00, // 6600: V6 = 0
+	0xE2, 0xA1, // E2A1: skip when the key in V2 is up
+	0x12, 0x1A, // 121A
+	0x66, 0x01, // 6601: V6 = 1, up for EXA1
+	0xD0, 0x01, // D001: draw, end of the frame
+	0x12, 0x0A, // 120A: check again
+}
+
+func newMachine(t *testing.T, events ...headless.Event) *headless.Machine {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	m, err := headless.NewMachine(log, interpreter.Quirks{DisplayWait: true}, 1, waitProgram)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Schedule(events...)
+
+	return m
+}
+
+func run(t *testing.T, m *headless.Machine, frames int) interpreter.State {
+	t.Helper()
+
+	if err := m.RunFrames(frames); err != nil {
+		t.Fatal(err)
+	}
+
+	return m.Chip8().State()
+}
+
+func TestWaitKeyFinishesOnRelease(t *testing.T) {
+	m := newMachine(t,
+		headless.Event{Frame: 3, Key: 0x7, Pressed: true},
+		headless.Event{Frame: 6, Key: 0x7, Pressed: false},
+	)
+
+	before := run(t, m, 2)
+	if before.Registers[4] != 0 {
+		t.Fatal("FX0A did not wait")
+	}
+
+	// held down, still waiting
+	held := run(t, m, 3)
+	if held.Registers[4] != 0 {
+		t.Fatal("FX0A finished while the key is held")
+	}
+
+	if held.DelayTimer >= before.DelayTimer {
+		t.Errorf("DT stopped during the wait: %d, then %d", before.DelayTimer, held.DelayTimer)
+	}
+
+	released := run(t, m, 2)
+	if released.Registers[4] != 1 || released.Registers[2] != 0x7 {
+		t.Fatalf("after the release, V4, V2 = %d, %X, want 1, 7", released.Registers[4], released.Registers[2])
+	}
+
+	// V3 took DT when the wait ended, 6 frames after it started
+	if v3 := released.Registers[3]; v3 == 0 || v3 > 60-5 {
+		t.Errorf("V3 = %d, want DT a few frames after the wait started", v3)
+	}
+}
+
+func TestWaitKeyHeldBeforeTheWait(t *testing.T) {
+	m := newMachine(t,
+		headless.Event{Frame: 0, Key: 0xB, Pressed: true},
+		headless.Event{Frame: 4, Key: 0xB, Pressed: false},
+	)
+
+	if s := run(t, m, 4); s.Registers[4] != 0 {
+		t.Fatal("FX0A finished while the key is held")
+	}
+
+	if s := run(t, m, 1); s.Registers[4] != 1 || s.Registers[2] != 0xB {
+		t.Errorf("after the release, V4, V2 = %d, %X, want 1, B", s.Registers[4], s.Registers[2])
+	}
+}
+
+func TestSkipsReadTheSameState(t *testing.T) {
+	m := newMachine(t,
+		headless.Event{Frame: 1, Key: 0x2, Pressed: true},
+		headless.Event{Frame: 2, Key: 0x2, Pressed: false},
+		headless.Event{Frame: 5, Key: 0x2, Pressed: true},
+		headless.Event{Frame: 8, Key: 0x2, Pressed: false},
+	)
+
+	if s := run(t, m, 4); s.Registers[4] != 1 || s.Registers[5] != 0 || s.Registers[6] != 1 {
+		t.Fatalf("key up: V4, V5, V6 = %d, %d, %d, want 1, 0, 1", s.Registers[4], s.Registers[5], s.Registers[6])
+	}
+
+	if s := run(t, m, 2); s.Registers[5] != 1 || s.Registers[6] != 0 {
+		t.Errorf("key down: V5, V6 = %d, %d, want 1, 0", s.Registers[5], s.Registers[6])
+	}
+
+	if s := run(t, m, 3); s.Registers[5] != 0 || s.Registers[6] != 1 {
+		t.Errorf("key up again: V5, V6 = %d, %d, want 0, 1", s.Registers[5], s.Registers[6])
+	}
+}
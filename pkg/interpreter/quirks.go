@@ -0,0 +1,128 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/otaviohenrique/zamorak/pkg/decoder"
+)
+
+// Quirks toggles the behaviours that differ between CHIP-8 implementations.
+// Programs written for one interpreter often misbehave on another because
+// they rely on one of these.
+type Quirks struct {
+	VFReset     bool // 8XY1, 8XY2 and 8XY3 reset VF to 0
+	Memory      bool // FX55 and FX65 leave I pointing past the last register
+	Shifting    bool // 8XY6 and 8XYE shift VX in place instead of shifting VY into VX
+	Jumping     bool // BNNN jumps to XNN + VX instead of NNN + V0
+	Clipping    bool // sprites are clipped at the screen edges instead of wrapping around
+	DisplayWait bool // DXYN ends the frame, so at most one sprite is drawn per frame
+}
+
+var (
+	// Names used by ParseQuirks and Quirks.String, in a stable order.
+	QUIRK_NAMES = []string{"vfreset", "memory", "shifting", "jumping", "clipping", "displaywait"}
+)
+
+// QuirksForProfile returns the quirks of the platform's reference interpreter.
+func QuirksForProfile(p decoder.Platform) Quirks {
+	switch p {
+	case decoder.PlatformSCHIP, decoder.PlatformMEGACHIP:
+		return Quirks{Shifting: true, Jumping: true, Clipping: true}
+	case decoder.PlatformXOCHIP:
+		return Quirks{Memory: true}
+	}
+
+	// COSMAC VIP
+	return Quirks{VFReset: true, Memory: true, Clipping: true, DisplayWait: true}
+}
+
+func (q *Quirks) field(name string) (*bool, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "vfreset":
+		return &q.VFReset, nil
+	case "memory":
+		return &q.Memory, nil
+	case "shifting":
+		return &q.Shifting, nil
+	case "jumping":
+		return &q.Jumping, nil
+	case "clipping":
+		return &q.Clipping, nil
+	case "displaywait":
+		return &q.DisplayWait, nil
+	}
+
+	return nil, fmt.Errorf("unknown quirk %q", name)
+}
+
+// Get reports whether the named quirk is on.
+func (q Quirks) Get(name string) bool {
+	f, err := q.field(name)
+	if err != nil {
+		return false
+	}
+
+	return *f
+}
+
+// Set turns the named quirk on or off.
+func (q *Quirks) Set(name string, on bool) error {
+	f, err := q.field(name)
+	if err != nil {
+		return err
+	}
+
+	*f = on
+
+	return nil
+}
+
+// String lists every quirk as name=on or name=off, in the format accepted by
+// ParseQuirks.
+func (q Quirks) String() string {
+	parts := make([]string, 0, len(QUIRK_NAMES))
+
+	for _, name := range QUIRK_NAMES {
+		state := "off"
+		if q.Get(name) {
+			state = "on"
+		}
+
+		parts = append(parts, name+"="+state)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// ParseQuirks applies a comma separated list of overrides to base. Every item
+// is either a quirk name, which turns it on, or name=on|off.
+func ParseQuirks(base Quirks, spec string) (Quirks, error) {
+	q := base
+
+	for _, item := range strings.Split(spec, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+
+		name, value, found := strings.Cut(item, "=")
+
+		on := true
+		if found {
+			switch strings.ToLower(strings.TrimSpace(value)) {
+			case "on", "true", "1", "yes":
+				on = true
+			case "off", "false", "0", "no":
+				on = false
+			default:
+				return base, fmt.Errorf("invalid value %q for quirk %q", value, name)
+			}
+		}
+
+		if err := q.Set(name, on); err != nil {
+			return base, err
+		}
+	}
+
+	return q, nil
+}
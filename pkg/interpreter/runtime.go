@@ -0,0 +1,18 @@
+package interpreter
+
+import "github.com/otaviohenrique/zamorak/pkg/keypad"
+
+// Runtime is what the interpreter needs from the machine around it: a 64x32
+// monochrome display, a 16 key hex keypad and a beeper. engine.Runtime
+// provides one backed by a window, headless.Runtime one that lives only in
+// memory.
+type Runtime interface {
+	IsPixelSet(col int, row int) bool
+	Set(col int, row int, on bool)
+	ClearScreen()
+
+	PlayAudio()
+	StopAudio()
+
+	Keypad() *keypad.Keypad
+}
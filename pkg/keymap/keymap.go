@@ -0,0 +1,260 @@
+package keymap
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Keymap lists the host keys bound to every CHIP-8 key, by the names ebiten
+// gives them, ex: Digit1, Q, Numpad7. A CHIP-8 key may have several host
+// keys.
+//
+// The window reads keys by their position on a US keyboard, whatever labels
+// they have, so it reads keymaps through ParsePositions, while the terminal
+// reads the characters they type.
+//
+// Keymaps for one ROM are set in the roms section of the config file, keyed
+// by file name or SHA-1. It stands in for a ROM compatibility database,
+// none is bundled.
+type Keymap [16][]string
+
+var (
+	DEFAULT_PRESET = "qwerty"
+
+	// The COSMAC VIP keypad is laid out as
+	//
+	//	1 2 3 C
+	//	4 5 6 D
+	//	7 8 9 E
+	//	A 0 B F
+	//
+	// and the presets put it on a 4x4 block of the host keyboard, except hex
+	// which binds every key to the host key with the same label. qwerty
+	// names key positions, so it fits the window on every layout.
+	PRESETS = map[string]Keymap{
+		"qwerty": grid(
+			"Digit1 Digit2 Digit3 Digit4",
+			"Q W E R",
+			"A S D F",
+			"Z X C V",
+		),
+		"azerty": grid(
+			"Digit1 Digit2 Digit3 Digit4",
+			"A Z E R",
+			"Q S D F",
+			"W X C V",
+		),
+		"qwertz": grid(
+			"Digit1 Digit2 Digit3 Digit4",
+			"Q W E R",
+			"A S D F",
+			"Y X C V",
+		),
+		"dvorak": grid(
+			"Digit1 Digit2 Digit3 Digit4",
+			"Quote Comma Period P",
+			"A O E U",
+			"Semicolon Q J K",
+		),
+		"vip": grid(
+			"Numpad7 Numpad8 Numpad9 NumpadDivide",
+			"Numpad4 Numpad5 Numpad6 NumpadMultiply",
+			"Numpad1 Numpad2 Numpad3 NumpadSubtract",
+			"Numpad0 NumpadDecimal NumpadEnter NumpadAdd",
+		),
+		"hex": {
+			{"Digit0", "Numpad0"}, {"Digit1", "Numpad1"}, {"Digit2", "Numpad2"}, {"Digit3", "Numpad3"},
+			{"Digit4", "Numpad4"}, {"Digit5", "Numpad5"}, {"Digit6", "Numpad6"}, {"Digit7", "Numpad7"},
+			{"Digit8", "Numpad8"}, {"Digit9", "Numpad9"}, {"A"}, {"B"}, {"C"}, {"D"}, {"E"}, {"F"},
+		},
+	}
+
+	// presets naming the labels of a keyboard layout rather than positions
+	LABEL_PRESETS = []string{"azerty", "qwertz", "dvorak"}
+
+	// where the keys labeled differently on a layout are on a US keyboard,
+	// by label
+	LABEL_POSITIONS = map[string]map[string]string{
+		"azerty": {
+			"A": "Q", "Z": "W", "Q": "A", "W": "Z",
+			"M": "Semicolon", "Comma": "M", "Semicolon": "Comma",
+		},
+		"qwertz": {
+			"Y": "Z", "Z": "Y",
+		},
+		"dvorak": {
+			"Quote": "Q", "Comma": "W", "Period": "E", "P": "R", "Y": "T",
+			"F": "Y", "G": "U", "C": "I", "R": "O", "L": "P",
+			"O": "S", "E": "D", "U": "F", "I": "G", "D": "H",
+			"H": "J", "T": "K", "N": "L", "S": "Semicolon",
+			"Semicolon": "Z", "Q": "X", "J": "C", "K": "V", "X": "B",
+			"B": "N", "W": "Comma", "V": "Period", "Z": "Slash",
+		},
+	}
+
+	// CHIP-8 keys in the order the rows of the VIP keypad list them
+	VIP_LAYOUT = [16]byte{
+		0x1, 0x2, 0x3, 0xC,
+		0x4, 0x5, 0x6, 0xD,
+		0x7, 0x8, 0x9, 0xE,
+		0xA, 0x0, 0xB, 0xF,
+	}
+)
+
+// grid builds a keymap from four rows of host keys laid out like the VIP
+// keypad.
+func grid(rows ...string) Keymap {
+	var k Keymap
+
+	i := 0
+	for _, row := range rows {
+		for _, key := range strings.Fields(row) {
+			chip := VIP_LAYOUT[i]
+			k[chip] = append(k[chip], key)
+			i++
+		}
+	}
+
+	return k
+}
+
+// Presets returns the names of the presets, sorted.
+func Presets() []string {
+	names := make([]string, 0, len(PRESETS))
+	for name := range PRESETS {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Parse reads a keymap written as a preset name followed by overrides of
+// single CHIP-8 keys, ex: qwerty or azerty,0=Space/X,F=Enter. Host keys of
+// an override are separated by slashes and replace the ones of the preset.
+// An empty spec is the default preset.
+func Parse(spec string) (Keymap, error) {
+	parts := strings.Split(spec, ",")
+
+	name := strings.ToLower(strings.TrimSpace(parts[0]))
+	if name == "" {
+		name = DEFAULT_PRESET
+	}
+
+	preset, ok := PRESETS[name]
+	if !ok {
+		return Keymap{}, fmt.Errorf("unknown keymap %q, want one of %s", name, strings.Join(Presets(), ", "))
+	}
+
+	// copy, so overrides do not change the preset
+	var k Keymap
+	for i := range preset {
+		k[i] = append([]string(nil), preset[i]...)
+	}
+
+	for _, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		chip, keys, ok := strings.Cut(part, "=")
+		if !ok {
+			return Keymap{}, fmt.Errorf("invalid keymap override %q, want key=HostKey", part)
+		}
+
+		n, err := strconv.ParseUint(strings.TrimSpace(chip), 16, 8)
+		if err != nil || n > 0xF {
+			return Keymap{}, fmt.Errorf("invalid CHIP-8 key %q, want 0 to F", chip)
+		}
+
+		k[n] = nil
+		for _, key := range strings.Split(keys, "/") {
+			if key = strings.TrimSpace(key); key != "" {
+				k[n] = append(k[n], key)
+			}
+		}
+	}
+
+	return k, nil
+}
+
+// ParsePositions is Parse for frontends reading key positions. The keys of
+// a label preset, and of its overrides, are moved to the positions they have
+// on that layout, so azerty binds the same keys as qwerty on an AZERTY
+// keyboard.
+func ParsePositions(spec string) (Keymap, error) {
+	k, err := Parse(spec)
+	if err != nil || !ByLabel(spec) {
+		return k, err
+	}
+
+	name, _, _ := strings.Cut(spec, ",")
+	positions := LABEL_POSITIONS[strings.ToLower(strings.TrimSpace(name))]
+
+	for chip, keys := range k {
+		for i, key := range keys {
+			for label, position := range positions {
+				if strings.EqualFold(label, key) {
+					k[chip][i] = position
+					break
+				}
+			}
+		}
+	}
+
+	return k, nil
+}
+
+// ByLabel reports whether a keymap spec starts with one of LABEL_PRESETS.
+func ByLabel(spec string) bool {
+	name, _, _ := strings.Cut(spec, ",")
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	for _, preset := range LABEL_PRESETS {
+		if preset == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Lookup returns the CHIP-8 key a host key is bound to. Names are compared
+// without case.
+func (k Keymap) Lookup(host string) (byte, bool) {
+	for chip, keys := range k {
+		for _, key := range keys {
+			if strings.EqualFold(key, host) {
+				return byte(chip), true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// String renders the keymap laid out like the VIP keypad.
+func (k Keymap) String() string {
+	var b strings.Builder
+
+	for row := 0; row < 4; row++ {
+		var line strings.Builder
+
+		for _, chip := range VIP_LAYOUT[row*4 : row*4+4] {
+			keys := strings.Join(k[chip], "/")
+			if keys == "" {
+				keys = "-"
+			}
+
+			fmt.Fprintf(&line, "%X=%-16s", chip, keys)
+		}
+
+		b.WriteString(strings.TrimRight(line.String(), " "))
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package keymap
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	k, err := Parse("qwerty,0=Space/X,f=")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host string
+		chip byte
+		ok   bool
+	}{
+		{"Digit1", 0x1, true},
+		{"q", 0x4, true}, // names are compared without case
+		{"V", 0xF, false},
+		{"Space", 0x0, true},
+		{"X", 0x0, true},
+		{"Enter", 0, false},
+	}
+
+	for _, tt := range tests {
+		chip, ok := k.Lookup(tt.host)
+
+		if ok != tt.ok || (ok && chip != tt.chip) {
+			t.Errorf("Lookup(%q) = %X, %v, want %X, %v", tt.host, chip, ok, tt.chip, tt.ok)
+		}
+	}
+
+	// overrides do not change the preset
+	if chip, ok := PRESETS["qwerty"].Lookup("V"); !ok || chip != 0xF {
+		t.Errorf("qwerty preset changed by an override: V = %X, %v", chip, ok)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{"colemak", "qwerty,0", "qwerty,G=Space", "qwerty,10=Space"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) accepted", spec)
+		}
+	}
+}
+
+func TestByLabel(t *testing.T) {
+	tests := map[string]bool{
+		"":               false,
+		"qwerty":         false,
+		"hex,0=Space":    false,
+		"azerty":         true,
+		" Dvorak ,1=X":   true,
+		"qwertz,F=Enter": true,
+	}
+
+	for spec, want := range tests {
+		if got := ByLabel(spec); got != want {
+			t.Errorf("ByLabel(%q) = %v, want %v", spec, got, want)
+		}
+	}
+}
+
+func TestParsePositions(t *testing.T) {
+	// every label preset sits where qwerty does on its own layout
+	for _, name := range LABEL_PRESETS {
+		k, err := ParsePositions(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if k.String() != PRESETS["qwerty"].String() {
+			t.Errorf("%s positions:\n%s\nwant qwerty:\n%s", name, k, PRESETS["qwerty"])
+		}
+	}
+
+	// overrides name labels too
+	k, err := ParsePositions("azerty,0=A/M")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := k[0x0]; len(got) != 2 || got[0] != "Q" || got[1] != "Semicolon" {
+		t.Errorf("azerty override 0=A/M at %v, want Q/Semicolon", got)
+	}
+
+	// position presets are left as they are
+	k, err = ParsePositions("hex")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if k.String() != PRESETS["hex"].String() {
+		t.Errorf("hex changed:\n%s", k)
+	}
+}
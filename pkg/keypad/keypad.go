@@ -0,0 +1,118 @@
+package keypad
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	// edges kept for a program that is not reading them, older ones are
+	// dropped
+	MAX_EVENTS = 64
+)
+
+// Event is a key going down or up.
+type Event struct {
+	Key     byte
+	Pressed bool
+}
+
+// Keypad is the state of the 16 key hex keypad. Frontends report keys going
+// down and up, the interpreter reads which keys are held and the edges since
+// it last looked. It is safe to use from several goroutines.
+type Keypad struct {
+	mu      sync.Mutex
+	pressed [16]bool
+	events  []Event
+	polled  [16]time.Time // last time the program checked every key
+}
+
+func New() *Keypad {
+	k := new(Keypad)
+
+	k.events = make([]Event, 0, MAX_EVENTS)
+
+	return k
+}
+
+// Set records the state of a key, adding an edge when it changes.
+func (k *Keypad) Set(key byte, pressed bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key &= 0x0F
+
+	if k.pressed[key] == pressed {
+		return
+	}
+
+	k.pressed[key] = pressed
+
+	if len(k.events) == MAX_EVENTS {
+		k.events = append(k.events[:0], k.events[1:]...)
+	}
+
+	k.events = append(k.events, Event{Key: key, Pressed: pressed})
+}
+
+func (k *Keypad) Press(key byte) {
+	k.Set(key, true)
+}
+
+func (k *Keypad) Release(key byte) {
+	k.Set(key, false)
+}
+
+// IsPressed reports whether a key is held.
+func (k *Keypad) IsPressed(key byte) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.pressed[key&0x0F]
+}
+
+// Poll is IsPressed for the program: it also remembers that the key was
+// checked, so frontends can show which keys a game is looking at.
+func (k *Keypad) Poll(key byte) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key &= 0x0F
+	k.polled[key] = time.Now()
+
+	return k.pressed[key]
+}
+
+// Polled returns the keys the program checked in the last d.
+func (k *Keypad) Polled(d time.Duration) [16]bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var polled [16]bool
+
+	since := time.Now().Add(-d)
+	for key, t := range k.polled {
+		polled[key] = t.After(since)
+	}
+
+	return polled
+}
+
+// Pressed returns the state of every key.
+func (k *Keypad) Pressed() [16]bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.pressed
+}
+
+// Events returns the edges since the last call, oldest first.
+func (k *Keypad) Events() []Event {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	events := append([]Event(nil), k.events...)
+	k.events = k.events[:0]
+
+	return events
+}
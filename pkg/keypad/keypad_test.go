@@ -0,0 +1,77 @@
+package keypad
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEdges(t *testing.T) {
+	k := New()
+
+	k.Press(0x5)
+	k.Press(0x5) // already down, no edge
+	k.Press(0xA)
+	k.Release(0x5)
+	k.Release(0x3) // already up, no edge
+
+	want := []Event{{0x5, true}, {0xA, true}, {0x5, false}}
+	if got := k.Events(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+
+	if got := k.Events(); len(got) != 0 {
+		t.Errorf("events read twice = %v, want none", got)
+	}
+
+	if !k.IsPressed(0xA) || k.IsPressed(0x5) {
+		t.Errorf("pressed = %v, want only A", k.Pressed())
+	}
+}
+
+func TestKeysAreMasked(t *testing.T) {
+	k := New()
+
+	k.Press(0x1F)
+
+	if !k.IsPressed(0xF) || !k.Poll(0x2F) {
+		t.Errorf("key 1F is not key F")
+	}
+}
+
+func TestOldEdgesAreDropped(t *testing.T) {
+	old := MAX_EVENTS
+	MAX_EVENTS = 4
+	defer func() { MAX_EVENTS = old }()
+
+	k := New()
+
+	for i := 0; i < 3; i++ {
+		k.Press(byte(i))
+		k.Release(byte(i))
+	}
+
+	want := []Event{{0x1, true}, {0x1, false}, {0x2, true}, {0x2, false}}
+	if got := k.Events(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestPolled(t *testing.T) {
+	k := New()
+
+	if k.Poll(0x4) {
+		t.Error("key 4 is pressed")
+	}
+
+	polled := k.Polled(time.Minute)
+	for key, p := range polled {
+		if p != (key == 0x4) {
+			t.Errorf("key %X polled = %v", key, p)
+		}
+	}
+
+	if polled := k.Polled(0); polled[0x4] {
+		t.Error("key 4 polled in the last 0s")
+	}
+}
@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// Held writes logs through to a writer, except while held, when they are kept
+// in memory, such as while the terminal they go to draws a game. Only the last
+// max bytes are kept.
+type Held struct {
+	mu      sync.Mutex
+	w       io.Writer
+	max     int
+	holding bool
+	buf     []byte
+	dropped bool // older logs were dropped to keep max bytes
+}
+
+func NewHeld(w io.Writer, max int) *Held {
+	h := new(Held)
+
+	h.w = w
+	h.max = max
+
+	return h
+}
+
+func (h *Held) Write(p []byte) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.holding {
+		return h.w.Write(p)
+	}
+
+	h.buf = append(h.buf, p...)
+
+	if len(h.buf) > h.max {
+		cut := len(h.buf) - h.max
+
+		// drop whole lines
+		if i := bytes.IndexByte(h.buf[cut:], '\n'); i >= 0 {
+			cut += i + 1
+		} else {
+			cut = len(h.buf)
+		}
+
+		h.buf = append(h.buf[:0], h.buf[cut:]...)
+		h.dropped = true
+	}
+
+	return len(p), nil
+}
+
+// Hold keeps the logs written from now on until Release.
+func (h *Held) Hold() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.holding = true
+}
+
+// Release writes out the logs kept since Hold, and writes the next ones
+// through.
+func (h *Held) Release() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.holding = false
+
+	if h.dropped {
+		if _, err := io.WriteString(h.w, "... earlier logs dropped\n"); err != nil {
+			return err
+		}
+	}
+
+	buf := h.buf
+	h.buf, h.dropped = nil, false
+
+	_, err := h.w.Write(buf)
+
+	return err
+}
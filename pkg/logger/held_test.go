@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHeld(t *testing.T) {
+	var out bytes.Buffer
+	h := NewHeld(&out, 1024)
+
+	h.Write([]byte("before\n"))
+	h.Hold()
+	h.Write([]byte("during\n"))
+
+	if got := out.String(); got != "before\n" {
+		t.Fatalf("while held, output = %q, want %q", got, "before\n")
+	}
+
+	if err := h.Release(); err != nil {
+		t.Fatal(err)
+	}
+	h.Write([]byte("after\n"))
+
+	if got, want := out.String(), "before\nduring\nafter\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestHeldKeepsTheLastLines(t *testing.T) {
+	var out bytes.Buffer
+	h := NewHeld(&out, 10)
+
+	h.Hold()
+	h.Write([]byte("first\n"))
+	h.Write([]byte("second\n"))
+	h.Write([]byte("third\n"))
+	h.Release()
+
+	if got, want := out.String(), "... earlier logs dropped\nthird\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -1,16 +1,189 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"sort"
 	"strings"
 )
 
+// Subsystem is a part of the emulator with its own log level.
+type Subsystem string
+
+const (
+	CPU       Subsystem = "cpu"
+	DISPLAY   Subsystem = "display"
+	INPUT     Subsystem = "input"
+	AUDIO     Subsystem = "audio"
+	SCHEDULER Subsystem = "scheduler"
+)
+
+var (
+	SUBSYSTEMS = []Subsystem{CPU, DISPLAY, INPUT, AUDIO, SCHEDULER}
+
+	// attribute that tags the records of a subsystem
+	SUBSYSTEM_KEY = "subsystem"
+
+	// logs kept in memory by a Held writer while it is held
+	HELD_LOG_SIZE = 1 << 20
+)
+
+// Config describes where logs go and how verbose every subsystem is.
+type Config struct {
+	Level      string               // level of records without a subsystem, and default for the others
+	Levels     map[Subsystem]string // per subsystem overrides
+	Format     string               // text or json
+	File       string               // log to this file instead of Output
+	Output     io.Writer            // where logs go without a file, stdout when nil
+	MaxSize    int64                // rotate the file once it grows past this many bytes, 0 never rotates
+	MaxBackups int                  // rotated files to keep
+	Source     bool                 // add the source file and line to every record
+}
+
 func NewLogger(lvl string) *slog.Logger {
-	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level:     selectLogLevel(lvl),
-		AddSource: true,
-	}))
+	log, _, _ := New(Config{Level: lvl, Source: true})
+
+	return log
+}
+
+// New builds a logger from a config. The returned closer closes the log file,
+// if there is one.
+func New(cfg Config) (*slog.Logger, io.Closer, error) {
+	var w io.Writer = os.Stdout
+	var closer io.Closer = io.NopCloser(nil)
+
+	if cfg.Output != nil {
+		w = cfg.Output
+	}
+
+	if cfg.File != "" {
+		f, err := OpenRotating(cfg.File, cfg.MaxSize, cfg.MaxBackups)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		w = f
+		closer = f
+	}
+
+	levels := make(map[Subsystem]slog.Level)
+	for name, lvl := range cfg.Levels {
+		if !isSubsystem(name) {
+			return nil, nil, fmt.Errorf("unknown log subsystem %q, want one of %s", name, subsystemNames())
+		}
+
+		levels[name] = selectLogLevel(lvl)
+	}
+
+	base := selectLogLevel(cfg.Level)
+
+	// the inner handler lets everything through, levels are checked by the
+	// subsystem handler
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: cfg.Source}
+
+	var h slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		closer.Close()
+		return nil, nil, fmt.Errorf("unknown log format %q, want text or json", cfg.Format)
+	}
+
+	return slog.New(&subsystemHandler{inner: h, levels: levels, level: base}), closer, nil
+}
+
+// For returns a logger whose records are tagged with, and filtered by the
+// level of, a subsystem.
+func For(log *slog.Logger, s Subsystem) *slog.Logger {
+	return log.With(SUBSYSTEM_KEY, string(s))
+}
+
+// ParseLevels reads per subsystem levels written as cpu=debug,audio=warn.
+func ParseLevels(spec string) (map[Subsystem]string, error) {
+	levels := make(map[Subsystem]string)
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, lvl, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid log level %q, want subsystem=level", part)
+		}
+
+		s := Subsystem(strings.ToLower(strings.TrimSpace(name)))
+		if !isSubsystem(s) {
+			return nil, fmt.Errorf("unknown log subsystem %q, want one of %s", name, subsystemNames())
+		}
+
+		levels[s] = strings.TrimSpace(lvl)
+	}
+
+	return levels, nil
+}
+
+func isSubsystem(s Subsystem) bool {
+	for _, known := range SUBSYSTEMS {
+		if s == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+func subsystemNames() string {
+	names := make([]string, len(SUBSYSTEMS))
+	for i, s := range SUBSYSTEMS {
+		names[i] = string(s)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, ", ")
+}
+
+// subsystemHandler drops records below the level of the subsystem a logger
+// was tagged with by For.
+type subsystemHandler struct {
+	inner  slog.Handler
+	levels map[Subsystem]slog.Level
+	level  slog.Level
+}
+
+func (h *subsystemHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *subsystemHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *subsystemHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	level := h.level
+
+	for _, a := range attrs {
+		if a.Key != SUBSYSTEM_KEY {
+			continue
+		}
+
+		if l, ok := h.levels[Subsystem(a.Value.String())]; ok {
+			level = l
+		}
+	}
+
+	return &subsystemHandler{inner: h.inner.WithAttrs(attrs), levels: h.levels, level: level}
+}
+
+func (h *subsystemHandler) WithGroup(name string) slog.Handler {
+	return &subsystemHandler{inner: h.inner.WithGroup(name), levels: h.levels, level: h.level}
 }
 
 func selectLogLevel(lvl string) slog.Level {
@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutput(t *testing.T) {
+	var out bytes.Buffer
+
+	log, closer, err := New(Config{Level: "INFO", Output: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+
+	log.Info("Program stopped")
+	For(log, CPU).Debug("hidden")
+
+	if got := out.String(); !strings.Contains(got, "Program stopped") || strings.Contains(got, "hidden") {
+		t.Errorf("output = %q, want the info record only", got)
+	}
+}
+
+func TestFileWinsOverOutput(t *testing.T) {
+	var out bytes.Buffer
+	path := filepath.Join(t.TempDir(), "zamorak.log")
+
+	log, closer, err := New(Config{Level: "INFO", Output: &out, File: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log.Info("to the file")
+	closer.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Len() != 0 || !strings.Contains(string(data), "to the file") {
+		t.Errorf("output = %q, file = %q, want the record in the file only", out.String(), data)
+	}
+}
@@ -0,0 +1,146 @@
+package palette
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Palette holds the colors of a pixel depending on the planes lit in it: 0
+// is the background, 1 the first plane, 2 the second plane and 3 both. Only
+// XO-CHIP programs draw on the second plane.
+type Palette [4]color.RGBA
+
+var (
+	DEFAULT_PRESET = "classic"
+
+	PRESETS = map[string]Palette{
+		"classic":       {rgb(0x000000), rgb(0xFFFFFF), rgb(0xAAAAAA), rgb(0x555555)},
+		"amber":         {rgb(0x1A0F00), rgb(0xFFB000), rgb(0x996A00), rgb(0xFFD780)},
+		"green":         {rgb(0x001A05), rgb(0x33FF66), rgb(0x1A993D), rgb(0xA0FFB8)},
+		"lcd":           {rgb(0x9BBC0F), rgb(0x0F380F), rgb(0x306230), rgb(0x8BAC0F)},
+		"high-contrast": {rgb(0x000000), rgb(0xFFFF00), rgb(0x00FFFF), rgb(0xFFFFFF)},
+
+		// Okabe-Ito colors, told apart with any kind of color blindness
+		"colorblind": {rgb(0x000000), rgb(0xE69F00), rgb(0x56B4E9), rgb(0xF0E442)},
+	}
+)
+
+func rgb(hex uint32) color.RGBA {
+	return color.RGBA{R: uint8(hex >> 16), G: uint8(hex >> 8), B: uint8(hex), A: 0xFF}
+}
+
+// Presets returns the names of the presets, sorted.
+func Presets() []string {
+	names := make([]string, 0, len(PRESETS))
+	for name := range PRESETS {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Parse reads a palette written as a preset name followed by overrides of
+// single colors, ex: amber or classic,1=#FF8800,2=#0088FF, or as a list of
+// colors starting with the background, ex: #000000,#33FF66. Colors that are
+// not listed are taken from the default preset. An empty spec is the default
+// preset.
+func Parse(spec string) (Palette, error) {
+	parts := strings.Split(spec, ",")
+
+	first := strings.ToLower(strings.TrimSpace(parts[0]))
+
+	if strings.HasPrefix(first, "#") {
+		p := PRESETS[DEFAULT_PRESET]
+
+		if len(parts) > len(p) {
+			return Palette{}, fmt.Errorf("palette %q has %d colors, want at most %d", spec, len(parts), len(p))
+		}
+
+		for i, part := range parts {
+			c, err := ParseColor(part)
+			if err != nil {
+				return Palette{}, err
+			}
+
+			p[i] = c
+		}
+
+		return p, nil
+	}
+
+	if first == "" {
+		first = DEFAULT_PRESET
+	}
+
+	p, ok := PRESETS[first]
+	if !ok {
+		return Palette{}, fmt.Errorf("unknown palette %q, want one of %s or #RRGGBB colors", first, strings.Join(Presets(), ", "))
+	}
+
+	for _, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		index, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Palette{}, fmt.Errorf("invalid palette override %q, want index=#RRGGBB", part)
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(index))
+		if err != nil || n < 0 || n >= len(p) {
+			return Palette{}, fmt.Errorf("invalid palette index %q, want 0 to %d", index, len(p)-1)
+		}
+
+		c, err := ParseColor(value)
+		if err != nil {
+			return Palette{}, err
+		}
+
+		p[n] = c
+	}
+
+	return p, nil
+}
+
+// ParseColor reads a color written as #RRGGBB or #RGB.
+func ParseColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, want #RRGGBB", s)
+	}
+
+	return rgb(uint32(n)), nil
+}
+
+// Background returns the color of unlit pixels.
+func (p Palette) Background() color.RGBA {
+	return p[0]
+}
+
+// Color returns the color of a pixel given the planes lit in it, plane 1 in
+// bit 0 and plane 2 in bit 1.
+func (p Palette) Color(planes byte) color.RGBA {
+	return p[planes&0x3]
+}
+
+// String writes the palette as a list of colors Parse reads back.
+func (p Palette) String() string {
+	colors := make([]string, len(p))
+	for i, c := range p {
+		colors[i] = fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+	}
+
+	return strings.Join(colors, ",")
+}
@@ -0,0 +1,89 @@
+package palette
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    color.RGBA
+		wantErr bool
+	}{
+		{in: "#FF8800", want: color.RGBA{R: 0xFF, G: 0x88, B: 0x00, A: 0xFF}},
+		{in: " #ff8800 ", want: color.RGBA{R: 0xFF, G: 0x88, B: 0x00, A: 0xFF}},
+		{in: "0088FF", want: color.RGBA{R: 0x00, G: 0x88, B: 0xFF, A: 0xFF}},
+		{in: "#F80", want: color.RGBA{R: 0xFF, G: 0x88, B: 0x00, A: 0xFF}},
+		{in: "#FF88", wantErr: true},
+		{in: "#FF880000", wantErr: true},
+		{in: "#GG8800", wantErr: true},
+		{in: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseColor(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseColor(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("ParseColor(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	classic := PRESETS["classic"]
+
+	amberOverride := PRESETS["amber"]
+	amberOverride[1] = rgb(0xFF8800)
+	amberOverride[2] = rgb(0x0088FF)
+
+	listed := classic
+	listed[0] = rgb(0x102030)
+	listed[1] = rgb(0x33FF66)
+
+	tests := []struct {
+		spec    string
+		want    Palette
+		wantErr bool
+	}{
+		{spec: "", want: classic},
+		{spec: "AMBER", want: PRESETS["amber"]},
+		{spec: "amber,1=#FF8800, 2=#0088FF", want: amberOverride},
+		{spec: "amber,", want: PRESETS["amber"]},
+		{spec: "#102030,#33FF66", want: listed},
+		{spec: "#000,#111,#222,#333,#444", wantErr: true},
+		{spec: "sepia", wantErr: true},
+		{spec: "amber,1", wantErr: true},
+		{spec: "amber,4=#FFFFFF", wantErr: true},
+		{spec: "amber,1=orange", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	for _, name := range Presets() {
+		p, err := Parse(PRESETS[name].String())
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if p != PRESETS[name] {
+			t.Errorf("%s: read back %s, want %s", name, p, PRESETS[name])
+		}
+	}
+}
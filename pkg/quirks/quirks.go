@@ -0,0 +1,273 @@
+package quirks
+
+import (
+	"io"
+	"log/slog"
+	"math/bits"
+	"math/rand"
+	"sync"
+
+	"github.com/otaviohenrique/zamorak/pkg/decoder"
+	"github.com/otaviohenrique/zamorak/pkg/headless"
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+)
+
+// Options controls how a program is exercised.
+type Options struct {
+	Frames int                // frames to run under every permutation
+	Seed   int64              // seeds CXNN and the random input
+	Base   interpreter.Quirks // quirks of the profile the program is expected to run on
+	Events []headless.Event   // scripted input, random input is generated when empty
+}
+
+// Execution is an instruction affected by a quirk, recorded the first time it
+// runs at a given address.
+type Execution struct {
+	Frame  int
+	PC     uint16
+	Opcode uint16
+}
+
+// Run is the outcome of running the program under one set of quirks.
+type Run struct {
+	Quirks   interpreter.Quirks
+	Hashes   []uint64               // framebuffer hash at the end of every frame
+	Executed map[string][]Execution // instructions affected by each quirk
+	Fault    error                  // set when the program crashed the interpreter
+}
+
+// Divergence describes what toggling a single quirk of the base set does.
+type Divergence struct {
+	Quirk      string
+	Frame      int         // first frame where the screen differs, -1 when it never does
+	Executions []Execution // instructions affected by the quirk up to that frame
+}
+
+// Report is the result of Detect.
+type Report struct {
+	Base        interpreter.Quirks
+	Recommended interpreter.Quirks
+	Divergences []Divergence
+	Runs        []Run
+}
+
+// Detect runs the program headlessly under every permutation of the quirks,
+// with the same input and random numbers, and compares the screens. A quirk
+// whose setting changes the screen matters for the program; the recommended
+// set is the one that runs without faulting and shows the most distinct
+// frames, preferring the one closest to the base set on ties.
+func Detect(programData []byte, opts Options) (Report, error) {
+	events := opts.Events
+	if len(events) == 0 {
+		events = RandomInput(opts.Seed, opts.Frames)
+	}
+
+	total := 1 << len(interpreter.QUIRK_NAMES)
+	runs := make([]Run, total)
+	errs := make([]error, total)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			runs[i], errs[i] = run(programData, permutation(opts.Base, i), opts.Seed, opts.Frames, events)
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return Report{}, err
+		}
+	}
+
+	report := Report{Base: opts.Base, Runs: runs}
+
+	// permutation 0 is the base set, permutation 1<<n toggles only quirk n
+	for n, name := range interpreter.QUIRK_NAMES {
+		report.Divergences = append(report.Divergences, diverge(name, runs[0], runs[1<<n]))
+	}
+
+	best := 0
+	for i := 1; i < total; i++ {
+		switch {
+		case better(runs[i], runs[best]):
+			best = i
+		case !better(runs[best], runs[i]) && bits.OnesCount(uint(i)) < bits.OnesCount(uint(best)):
+			best = i
+		}
+	}
+
+	report.Recommended = runs[best].Quirks
+
+	return report, nil
+}
+
+// RandomInput presses a random key every now and then, for a few frames.
+func RandomInput(seed int64, frames int) []headless.Event {
+	random := rand.New(rand.NewSource(seed))
+	events := []headless.Event{}
+
+	for frame := 0; frame < frames; frame++ {
+		if random.Intn(30) != 0 {
+			continue
+		}
+
+		key := byte(random.Intn(16))
+		hold := 1 + random.Intn(10)
+
+		events = append(events,
+			headless.Event{Frame: frame, Key: key, Pressed: true},
+			headless.Event{Frame: frame + hold, Key: key, Pressed: false},
+		)
+	}
+
+	return events
+}
+
+// permutation toggles the quirks of base whose bit is set in i.
+func permutation(base interpreter.Quirks, i int) interpreter.Quirks {
+	q := base
+
+	for n, name := range interpreter.QUIRK_NAMES {
+		if i&(1<<n) != 0 {
+			q.Set(name, !q.Get(name))
+		}
+	}
+
+	return q
+}
+
+func run(programData []byte, q interpreter.Quirks, seed int64, frames int, events []headless.Event) (Run, error) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	m, err := headless.NewMachine(log, q, seed, programData)
+	if err != nil {
+		return Run{}, err
+	}
+
+	m.Schedule(events...)
+
+	result := Run{Quirks: q, Executed: make(map[string][]Execution)}
+
+	// an instruction can be affected by several quirks, each records it once
+	type key struct {
+		name   string
+		pc     uint16
+		opcode uint16
+	}
+	seen := make(map[key]bool)
+
+	m.Chip8().AddHook(func(c *interpreter.Chip8, pc uint16, instr decoder.Instruction) {
+		for _, name := range affectedBy(c, instr) {
+			k := key{name: name, pc: pc, opcode: instr.Opcode}
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+
+			e := Execution{Frame: m.Frame(), PC: pc, Opcode: instr.Opcode}
+			result.Executed[name] = append(result.Executed[name], e)
+		}
+	})
+
+	for i := 0; i < frames; i++ {
+		if result.Fault = m.RunFrame(); result.Fault != nil {
+			break
+		}
+
+		result.Hashes = append(result.Hashes, m.Runtime().Framebuffer().Hash())
+	}
+
+	return result, nil
+}
+
+// affectedBy returns the quirks that change the outcome of instr given the
+// current registers.
+func affectedBy(c *interpreter.Chip8, instr decoder.Instruction) []string {
+	v := c.Registers()
+
+	switch instr.Kind {
+	case 0x8:
+		switch instr.N {
+		case 0x1, 0x2, 0x3:
+			return []string{"vfreset"}
+		case 0x6, 0xE:
+			if instr.X != instr.Y {
+				return []string{"shifting"}
+			}
+		}
+	case 0xB:
+		if instr.X != 0x0 {
+			return []string{"jumping"}
+		}
+	case 0xD:
+		if int(v[instr.X]%64)+8 > 64 || int(v[instr.Y]%32)+int(instr.N) > 32 {
+			return []string{"clipping", "displaywait"}
+		}
+
+		return []string{"displaywait"}
+	case 0xF:
+		if instr.NN == 0x55 || instr.NN == 0x65 {
+			return []string{"memory"}
+		}
+	}
+
+	return nil
+}
+
+func diverge(name string, base Run, toggled Run) Divergence {
+	d := Divergence{Quirk: name, Frame: -1}
+
+	frames := len(base.Hashes)
+	if len(toggled.Hashes) > frames {
+		frames = len(toggled.Hashes)
+	}
+
+	for frame := 0; frame < frames; frame++ {
+		// a run that faulted has no hash for the frames after the fault
+		if frame >= len(base.Hashes) || frame >= len(toggled.Hashes) || base.Hashes[frame] != toggled.Hashes[frame] {
+			d.Frame = frame
+			break
+		}
+	}
+
+	if d.Frame < 0 {
+		return d
+	}
+
+	for _, e := range toggled.Executed[name] {
+		if e.Frame <= d.Frame {
+			d.Executions = append(d.Executions, e)
+		}
+	}
+
+	return d
+}
+
+// better reports whether a looks like a healthier run than b.
+func better(a Run, b Run) bool {
+	if (a.Fault == nil) != (b.Fault == nil) {
+		return a.Fault == nil
+	}
+
+	if len(a.Hashes) != len(b.Hashes) {
+		return len(a.Hashes) > len(b.Hashes)
+	}
+
+	return distinct(a.Hashes) > distinct(b.Hashes)
+}
+
+func distinct(hashes []uint64) int {
+	seen := make(map[uint64]bool)
+	for _, h := range hashes {
+		seen[h] = true
+	}
+
+	return len(seen)
+}
@@ -0,0 +1,27 @@
+package quirks
+
+import (
+	"testing"
+
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+)
+
+func TestRunRecordsEveryQuirkOfAnInstruction(t *testing.T) {
+	// V0 = 60, then a sprite drawn across the right edge, in a loop
+	program := []byte{
+		0x60, 0x3C, // 6000: V0 = 60
+		0xD0, 0x05, // D005: draw 5 rows at V0, V0
+		0x12, 0x02, // 1202: jump back to the draw
+	}
+
+	r, err := run(program, interpreter.Quirks{}, 1, 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"clipping", "displaywait"} {
+		if got := len(r.Executed[name]); got != 1 {
+			t.Errorf("%s recorded %d executions, want 1", name, got)
+		}
+	}
+}
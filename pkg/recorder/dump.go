@@ -0,0 +1,133 @@
+package recorder
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+	"github.com/otaviohenrique/zamorak/pkg/trace"
+)
+
+// Dump is everything needed to understand a crash report: what happened,
+// which program and settings were running, the last instructions and the
+// full machine state.
+type Dump struct {
+	Reason  string
+	Time    time.Time
+	ROM     string
+	Hash    string // SHA-1 of the program
+	Quirks  interpreter.Quirks
+	Cycles  uint64         // instructions executed in total
+	Records []trace.Record // last instructions, oldest first
+	State   interpreter.State
+}
+
+// Write writes the dump as text. Every instruction is followed by the
+// registers it changed.
+func (d *Dump) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "zamorak flight recorder dump\n\n")
+	fmt.Fprintf(bw, "reason:  %s\n", d.Reason)
+	fmt.Fprintf(bw, "time:    %s\n", d.Time.Format(time.RFC3339))
+	fmt.Fprintf(bw, "rom:     %s\n", d.ROM)
+	fmt.Fprintf(bw, "sha1:    %s\n", d.Hash)
+	fmt.Fprintf(bw, "quirks:  %s\n", d.Quirks)
+	fmt.Fprintf(bw, "cycles:  %d\n", d.Cycles)
+
+	fmt.Fprintf(bw, "\nlast %d instructions:\n", len(d.Records))
+	for i, r := range d.Records {
+		next := d.final()
+		if i+1 < len(d.Records) {
+			next = d.Records[i+1]
+		}
+
+		fmt.Fprintf(bw, "%s", r)
+		if changes := delta(r, next); len(changes) > 0 {
+			fmt.Fprintf(bw, "  ; %s", strings.Join(changes, " "))
+		}
+		fmt.Fprintln(bw)
+	}
+
+	s := d.State
+	fmt.Fprintf(bw, "\nstate:\n")
+	depth := s.SP + 1
+	fmt.Fprintf(bw, "PC=%03X I=%03X SP=%d DT=%02X ST=%02X\n", s.PC, s.I, depth, s.DelayTimer, s.SoundTimer)
+	fmt.Fprintf(bw, "V=% X\n", s.Registers[:])
+	fmt.Fprintf(bw, "stack=")
+	for i := 0; i < depth && i < len(s.Stack); i++ {
+		fmt.Fprintf(bw, " %03X", s.Stack[i])
+	}
+	fmt.Fprintln(bw)
+
+	fmt.Fprintf(bw, "\nmemory:\n%s", hex.Dump(s.Memory[:]))
+
+	return bw.Flush()
+}
+
+// Save writes the dump to a new file in dir and returns its path.
+func (d *Dump) Save(dir string) (string, error) {
+	path := filepath.Join(dir, FileName(d.ROM, d.Time))
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+
+	if err := d.Write(f); err != nil {
+		f.Close()
+		return "", err
+	}
+
+	return path, f.Close()
+}
+
+// final is the state after the last recorded instruction, in the same shape
+// as a record.
+func (d *Dump) final() trace.Record {
+	s := d.State
+
+	return trace.Record{
+		PC: s.PC,
+		V:  s.Registers,
+		I:  s.I,
+		SP: byte(s.SP + 1),
+		DT: s.DelayTimer,
+		ST: s.SoundTimer,
+	}
+}
+
+// delta lists the registers that differ between two records.
+func delta(a trace.Record, b trace.Record) []string {
+	var changes []string
+
+	for i := range a.V {
+		if a.V[i] != b.V[i] {
+			changes = append(changes, fmt.Sprintf("V%X=%02X", i, b.V[i]))
+		}
+	}
+
+	if a.I != b.I {
+		changes = append(changes, fmt.Sprintf("I=%03X", b.I))
+	}
+
+	if a.SP != b.SP {
+		changes = append(changes, fmt.Sprintf("SP=%d", b.SP))
+	}
+
+	if a.DT != b.DT {
+		changes = append(changes, fmt.Sprintf("DT=%02X", b.DT))
+	}
+
+	if a.ST != b.ST {
+		changes = append(changes, fmt.Sprintf("ST=%02X", b.ST))
+	}
+
+	return changes
+}
@@ -0,0 +1,123 @@
+package recorder
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/otaviohenrique/zamorak/pkg/decoder"
+	"github.com/otaviohenrique/zamorak/pkg/detect"
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+	"github.com/otaviohenrique/zamorak/pkg/trace"
+)
+
+var (
+	// Instructions kept by default, a bit more than one second of emulation
+	// at the default speed.
+	DEFAULT_SIZE = 1024
+)
+
+// Recorder is a flight recorder: it keeps the last instructions a Chip8
+// executed in a ring buffer, so that a dump can explain how a program got
+// where it crashed. Install Hook with Chip8.AddHook.
+type Recorder struct {
+	mu      sync.Mutex
+	records []trace.Record
+	next    int    // slot the next record is written to
+	cycle   uint64 // instructions recorded so far
+	rom     string
+	hash    string
+	quirks  interpreter.Quirks
+}
+
+func NewRecorder(size int, rom string, programData []byte, quirks interpreter.Quirks) *Recorder {
+	r := new(Recorder)
+
+	r.records = make([]trace.Record, 0, size)
+	r.rom = rom
+	r.hash = detect.Hash(programData)
+	r.quirks = quirks
+
+	return r
+}
+
+// Hook is an interpreter.Hook that records an instruction. It only copies
+// the registers, the mnemonic is worked out when a dump is taken.
+func (r *Recorder) Hook(c *interpreter.Chip8, pc uint16, instr decoder.Instruction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cap(r.records) == 0 {
+		return
+	}
+
+	delay, sound := c.Timers()
+	record := trace.Record{
+		Cycle:  r.cycle,
+		PC:     pc,
+		Opcode: instr.Opcode,
+		V:      c.Registers(),
+		I:      c.Index(),
+		SP:     byte(c.StackDepth()),
+		DT:     delay,
+		ST:     sound,
+		Fields: trace.FieldAll,
+	}
+
+	if len(r.records) < cap(r.records) {
+		r.records = append(r.records, record)
+	} else {
+		r.records[r.next] = record
+	}
+
+	r.next = (r.next + 1) % cap(r.records)
+	r.cycle++
+}
+
+// Snapshot takes a dump of a machine that is not running, on the goroutine
+// running it between two frames, see emulator.Emulator.Queue.
+func (r *Recorder) Snapshot(c *interpreter.Chip8, reason string) *Dump {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.snapshot(c, reason)
+}
+
+func (r *Recorder) snapshot(c *interpreter.Chip8, reason string) *Dump {
+	d := new(Dump)
+
+	d.Reason = reason
+	d.Time = time.Now()
+	d.ROM = r.rom
+	d.Hash = r.hash
+	d.Quirks = r.quirks
+	d.Cycles = r.cycle
+	d.State = c.State()
+
+	// oldest first: once the ring is full the oldest record is the next slot
+	d.Records = make([]trace.Record, 0, len(r.records))
+	if len(r.records) == cap(r.records) {
+		d.Records = append(d.Records, r.records[r.next:]...)
+		d.Records = append(d.Records, r.records[:r.next]...)
+	} else {
+		d.Records = append(d.Records, r.records...)
+	}
+
+	for i := range d.Records {
+		op := d.Records[i].Opcode
+		d.Records[i].Mnemonic = decoder.Decode(byte(op>>8), byte(op)).Mnemonic()
+	}
+
+	return d
+}
+
+// FileName returns the name a dump of the given ROM is saved under, ex:
+// zamorak-pong-20240102-150405.000.dump. Milliseconds keep dumps taken in
+// the same second apart.
+func FileName(rom string, t time.Time) string {
+	name := strings.TrimSuffix(filepath.Base(rom), filepath.Ext(rom))
+
+	return fmt.Sprintf("zamorak-%s-%s.dump", name, t.Format("20060102-150405.000"))
+}
@@ -0,0 +1,96 @@
+package recorder
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/otaviohenrique/zamorak/pkg/emulator"
+	"github.com/otaviohenrique/zamorak/pkg/headless"
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+)
+
+func TestRecorderKeepsTheLastInstructions(t *testing.T) {
+	// increments V0 forever
+	program := []byte{0x70, 0x01, 0x12, 0x00}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	m, err := headless.NewMachine(log, interpreter.Quirks{}, 1, program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := NewRecorder(4, "loop.ch8", program, interpreter.Quirks{})
+	m.Chip8().AddHook(rec.Hook)
+
+	if err := m.RunFrames(1); err != nil {
+		t.Fatal(err)
+	}
+
+	d := rec.Snapshot(m.Chip8(), "test")
+
+	if len(d.Records) != 4 {
+		t.Fatalf("%d records, want 4", len(d.Records))
+	}
+
+	for i, r := range d.Records {
+		if want := d.Cycles - 4 + uint64(i); r.Cycle != want {
+			t.Errorf("record %d is cycle %d, want %d", i, r.Cycle, want)
+		}
+
+		if r.Mnemonic == "" {
+			t.Errorf("record %d has no mnemonic", i)
+		}
+	}
+}
+
+func TestSnapshotWhileWaitingForAKey(t *testing.T) {
+	// FX0A waits for a key
+	program := []byte{0xF0, 0x0A}
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	e, err := emulator.New(log, interpreter.Quirks{}, "wait.ch8", program, headless.NewRuntime())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := NewRecorder(DEFAULT_SIZE, e.Path(), program, interpreter.Quirks{})
+	e.AddHook(rec.Hook)
+
+	for i := 0; i < 3; i++ {
+		e.Update()
+	}
+
+	// how a SIGQUIT dump is taken
+	var d *Dump
+	e.Queue(func(c *interpreter.Chip8) {
+		d = rec.Snapshot(c, "SIGQUIT")
+	})
+	e.Update()
+
+	if d == nil {
+		t.Fatal("no dump taken while waiting for a key")
+	}
+
+	if d.State.PC != 0x200 {
+		t.Errorf("dump at pc %03X, want 200", d.State.PC)
+	}
+}
+
+func TestFileNameKeepsDumpsOfTheSameSecondApart(t *testing.T) {
+	at := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	first := FileName("roms/pong.ch8", at)
+	second := FileName("roms/pong.ch8", at.Add(time.Millisecond))
+
+	if want := "zamorak-pong-20240102-150405.000.dump"; first != want {
+		t.Errorf("FileName = %q, want %q", first, want)
+	}
+
+	if first == second {
+		t.Errorf("dumps a millisecond apart are both named %q", first)
+	}
+}
@@ -0,0 +1,110 @@
+package romtest
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// file is the YAML layout of a test:
+//
+//	name: score goes up
+//	steps:
+//	  - load pong.ch8
+//	  - profile chip8
+//	  - seed 42
+//	  - press 5 at frame 10
+//	  - run 120 frames
+//	  - expect V3 == 0x10
+//	  - expect memory[0x300..0x302] == 1 2 3
+//	  - expect screen matches golden.txt
+type file struct {
+	Name  string   `yaml:"name"`
+	Steps []string `yaml:"steps"`
+}
+
+// Test is a parsed test file. Paths in its steps are relative to Dir.
+type Test struct {
+	Name  string
+	Path  string
+	Dir   string
+	Steps []Step
+}
+
+// Parse reads a test file.
+func Parse(path string) (*Test, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	t := &Test{Name: f.Name, Path: path, Dir: filepath.Dir(path)}
+
+	if t.Name == "" {
+		t.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	if len(f.Steps) == 0 {
+		return nil, fmt.Errorf("%s: no steps", path)
+	}
+
+	for i, text := range f.Steps {
+		step, err := parseStep(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s: step %d: %w", path, i+1, err)
+		}
+
+		t.Steps = append(t.Steps, step)
+	}
+
+	return t, nil
+}
+
+// Unparsed returns the failed result of a test file Parse could not read, so
+// it is reported along with the tests that ran.
+func Unparsed(path string, err error) Result {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	return Result{Test: &Test{Name: name, Path: path, Dir: filepath.Dir(path)}, Step: -1, Err: err}
+}
+
+// Find returns every .yaml and .yml file under root, or root itself if it is
+// a file.
+func Find(root string) ([]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{root}, nil
+	}
+
+	paths := []string{}
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if ext := filepath.Ext(path); !d.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
+
+	sort.Strings(paths)
+
+	return paths, err
+}
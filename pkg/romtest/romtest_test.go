@@ -0,0 +1,287 @@
+package romtest
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTest writes a test file with the given steps, and the files it refers
+// to, into a temporary directory and returns its path.
+func writeTest(t *testing.T, files map[string][]byte, steps ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("name: example\nsteps:\n")
+	for _, step := range steps {
+		b.WriteString("  - \"" + step + "\"\n")
+	}
+
+	path := filepath.Join(dir, "example.yaml")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestParseSteps(t *testing.T) {
+	tests := []struct {
+		step string
+		want string // as the step prints itself
+	}{
+		{"load pong.ch8", "load pong.ch8"},
+		{"profile SCHIP", "profile schip"},
+		{"profile xochip", "profile xochip"},
+		{"quirks shifting,jumping=off", "quirks shifting,jumping=off"},
+		{"seed 42", "seed 42"},
+		{"seed 0x10", "seed 16"},
+		{"press a", "press A"},
+		{"press 5 at frame 10", "press 5 at frame 10"},
+		{"release F at frame 0", "release F at frame 0"},
+		{"run 120 frames", "run 120 frames"},
+		{"run 1 frame", "run 1 frames"},
+		{"expect V3 == 0x10", "expect V3 == 0x10"},
+		{"expect vf != 1", "expect VF != 0x01"},
+		{"expect I >= 0x300", "expect I >= 0x300"},
+		{"expect PC < 0b1000000000", "expect PC < 0x200"},
+		{"expect DT <= 3", "expect DT <= 0x03"},
+		{"expect ST > 0", "expect ST > 0x00"},
+		{"expect SP == 0", "expect SP == 0x00"},
+		{"expect memory[0x300] == 7", "expect memory[0x300..0x300] == 0x07"},
+		{"expect memory[0x300..0x302] == 1 2 3", "expect memory[0x300..0x302] == 0x01 0x02 0x03"},
+		{"expect memory[0x300..0x302] == 1,0x02, 0b11", "expect memory[0x300..0x302] == 0x01 0x02 0x03"},
+		{"expect screen matches golden.txt", "expect screen matches golden.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.step, func(t *testing.T) {
+			test, err := Parse(writeTest(t, nil, tt.step))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := test.Steps[0].String(); got != tt.want {
+				t.Errorf("step = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMalformedSteps(t *testing.T) {
+	tests := []struct {
+		step string
+		err  string
+	}{
+		{"", "empty step"},
+		{"jump 0x200", "unknown step"},
+		{"load", "expected: load"},
+		{"load a.ch8 b.ch8", "expected: load"},
+		{"profile gameboy", "unknown platform"},
+		{"quirks nonsense", "quirk"},
+		{"seed", "expected: seed"},
+		{"seed many", "invalid syntax"},
+		{"press", "expected: press"},
+		{"press G", "invalid key"},
+		{"press 10", "invalid key"},
+		{"press 5 at 10", "expected: press"},
+		{"release 5 at frame -1", "invalid frame"},
+		{"run 10", "expected: run"},
+		{"run -1 frames", "invalid number of frames"},
+		{"run ten frames", "invalid number of frames"},
+		{"expect V3", "expected: expect"},
+		{"expect VG == 1", "unknown register"},
+		{"expect V3 =~ 1", "unknown operator"},
+		{"expect V3 == 1 2", "single value"},
+		{"expect I == 0x10000", "invalid 16 bit number"},
+		{"expect memory[0x300] != 1", "only be compared with =="},
+		{"expect memory[0x302..0x300] == 1 2 3", "ends before it starts"},
+		{"expect memory[0x300..0x302] == 1 2", "3 bytes, 2 values"},
+		{"expect memory[0x1000] == 1", "invalid 12 bit number"},
+		{"expect memory[0x300] == 256", "invalid 8 bit number"},
+		{"expect screen golden.txt", "expected: expect"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.step, func(t *testing.T) {
+			_, err := Parse(writeTest(t, nil, tt.step))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("error = %v, want one containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		return path
+	}
+
+	test, err := Parse(write("unnamed.yml", "steps: [load a.ch8]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if test.Name != "unnamed" || test.Dir != dir {
+		t.Errorf("name, dir = %q, %q, want %q, %q", test.Name, test.Dir, "unnamed", dir)
+	}
+
+	if _, err := Parse(write("empty.yaml", "name: empty\n")); err == nil || !strings.Contains(err.Error(), "no steps") {
+		t.Errorf("error = %v, want no steps", err)
+	}
+
+	if _, err := Parse(write("broken.yaml", "steps: [\n")); err == nil {
+		t.Error("broken YAML parsed")
+	}
+
+	if _, err := Parse(write("third.yaml", "steps: [load a.ch8, seed 1, run]\n")); err == nil || !strings.Contains(err.Error(), "step 3") {
+		t.Errorf("error = %v, want one naming step 3", err)
+	}
+}
+
+// stores 1 2 3 at 0x300, draws the digit 0 at the top left corner and sets
+// V7 once key 5 is down
+var program = []byte{
+	0x63, 0x10, // 6310: V3 = 0x10
+	0xA3, 0x00, // A300: I = 0x300
+	0x60, 0x01, // 6001: V0 = 1
+	0x61, 0x02, // 6102: V1 = 2
+	0x62, 0x03, // 6203: V2 = 3
+	0xF2, 0x55, // F255: store V0 to V2 at I
+	0x64, 0x00, // 6400: V4 = 0
+	0xF4, 0x29, // F429: I = digit V4
+	0xD4, 0x45, // D445: draw at V4, V4
+	0x66, 0x05, // 6605: V6 = 5, the key checked
+	0xE6, 0xA1, // E6A1: skip when the key in V6 is up
+	0x67, 0x01, // 6701: V7 = 1
+	0x12, 0x14, // 1214: check the key again
+}
+
+func TestRun(t *testing.T) {
+	rom := map[string][]byte{"test.ch8": program}
+
+	tests := []struct {
+		name  string
+		steps []string
+		step  int // of the failure, -1 when passing
+	}{
+		{"registers", []string{"load test.ch8", "run 2 frames", "expect V3 == 0x10", "expect V7 == 0", "expect I < 0x200"}, -1},
+		{"memory", []string{"load test.ch8", "run 2 frames", "expect memory[0x300..0x302] == 1 2 3"}, -1},
+		{"key", []string{"load test.ch8", "press 5 at frame 3", "run 2 frames", "expect V7 == 0", "run 2 frames", "expect V7 == 1"}, -1},
+		{"wrong register", []string{"load test.ch8", "run 2 frames", "expect V3 == 0x11"}, 2},
+		{"wrong memory", []string{"load test.ch8", "run 2 frames", "expect memory[0x300..0x302] == 1 2 4"}, 2},
+		{"no ROM", []string{"run 1 frames"}, 0},
+		{"missing ROM", []string{"load missing.ch8"}, 0},
+		{"seed after start", []string{"load test.ch8", "run 1 frames", "seed 2"}, 2},
+		{"key in the past", []string{"load test.ch8", "run 5 frames", "press 5 at frame 2"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test, err := Parse(writeTest(t, rom, tt.steps...))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := Run(test, false)
+
+			if tt.step < 0 {
+				if !r.Passed() {
+					t.Errorf("failed: %v", r.Err)
+				}
+
+				return
+			}
+
+			if r.Passed() || r.Step != tt.step {
+				t.Errorf("failed at step %d (%v), want step %d", r.Step, r.Err, tt.step)
+			}
+		})
+	}
+}
+
+func TestRunScreen(t *testing.T) {
+	path := writeTest(t, map[string][]byte{"test.ch8": program}, "load test.ch8", "run 2 frames", "expect screen matches screen.txt")
+
+	test, err := Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := Run(test, false); r.Passed() || !errors.Is(r.Err, os.ErrNotExist) {
+		t.Fatalf("without a golden, err = %v, want it missing", r.Err)
+	}
+
+	if r := Run(test, true); !r.Passed() {
+		t.Fatalf("update: %v", r.Err)
+	}
+
+	golden := filepath.Join(test.Dir, "screen.txt")
+
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := Run(test, false); !r.Passed() {
+		t.Fatalf("against the written golden: %v", r.Err)
+	}
+
+	// the digit 0 starts with a full row of 4 pixels, take one away
+	i := bytes.IndexByte(data, '#')
+	data[i] = '.'
+	if err := os.WriteFile(golden, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := Run(test, false); r.Passed() || !strings.Contains(r.Err.Error(), "does not match") {
+		t.Errorf("against a changed golden, err = %v, want a mismatch", r.Err)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	passing := &Test{Name: "passes", Path: "a.yaml", Steps: []Step{runStep{frames: 1}}}
+	failing := &Test{Name: "fails", Path: "b.yaml", Steps: []Step{runStep{frames: 1}}}
+
+	results := []Result{
+		{Test: passing},
+		{Test: failing, Step: 0, Err: errors.New("V3 is 0x00")},
+		Unparsed("dir/c.yaml", errors.New("dir/c.yaml: no steps")),
+	}
+
+	var out bytes.Buffer
+	if err := WriteJUnit(&out, "tests", results); err != nil {
+		t.Fatal(err)
+	}
+
+	report := out.String()
+
+	for _, want := range []string{
+		`<testsuites tests="3" failures="2"`,
+		`<testcase name="passes" classname="a.yaml"`,
+		`<failure message="run 1 frames">V3 is 0x00</failure>`,
+		`<testcase name="c" classname="dir/c.yaml"`,
+		`<failure message="invalid test file">dir/c.yaml: no steps</failure>`,
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report lacks %s:\n%s", want, report)
+		}
+	}
+}
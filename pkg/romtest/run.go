@@ -0,0 +1,112 @@
+package romtest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// Result is the outcome of a test. Err is nil when every step passed.
+type Result struct {
+	Test     *Test
+	Step     int // index of the failed step, -1 when the file could not be parsed
+	Err      error
+	Duration time.Duration
+}
+
+func (r Result) Passed() bool {
+	return r.Err == nil
+}
+
+// Run runs the steps of a test in order and stops at the first failure. With
+// update, screen expectations write their golden file instead of comparing.
+func Run(t *Test, update bool) Result {
+	start := time.Now()
+	r := &runner{test: t, update: update, seed: 1}
+
+	for i, step := range t.Steps {
+		if err := step.run(r); err != nil {
+			return Result{Test: t, Step: i, Err: fmt.Errorf("step %d, %s: %w", i+1, step, err), Duration: time.Since(start)}
+		}
+	}
+
+	return Result{Test: t, Duration: time.Since(start)}
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results as a JUnit XML report with a single suite.
+func WriteJUnit(w io.Writer, suite string, results []Result) error {
+	s := junitSuite{Name: suite, Tests: len(results)}
+	total := time.Duration(0)
+
+	for _, r := range results {
+		c := junitCase{Name: r.Test.Name, Classname: r.Test.Path, Time: seconds(r.Duration)}
+
+		if !r.Passed() {
+			s.Failures++
+			message := "invalid test file"
+			if r.Step >= 0 {
+				message = r.Test.Steps[r.Step].String()
+			}
+
+			c.Failure = &junitFailure{Message: message, Text: r.Err.Error()}
+		}
+
+		total += r.Duration
+		s.Cases = append(s.Cases, c)
+	}
+
+	s.Time = seconds(total)
+
+	report := junitSuites{Tests: s.Tests, Failures: s.Failures, Time: s.Time, Suites: []junitSuite{s}}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
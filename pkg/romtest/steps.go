@@ -0,0 +1,520 @@
+package romtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/otaviohenrique/zamorak/pkg/decoder"
+	"github.com/otaviohenrique/zamorak/pkg/display"
+	"github.com/otaviohenrique/zamorak/pkg/headless"
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+)
+
+// Step is one line of a test.
+type Step interface {
+	String() string
+	run(r *runner) error
+}
+
+func parseStep(text string) (Step, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty step")
+	}
+
+	args := fields[1:]
+
+	switch strings.ToLower(fields[0]) {
+	case "load":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected: load <rom>")
+		}
+
+		return loadStep{path: args[0]}, nil
+	case "profile":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected: profile <chip8|schip|xochip|megachip>")
+		}
+
+		platform, err := decoder.ParsePlatform(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return profileStep{platform: platform}, nil
+	case "quirks":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected: quirks <name[=on|off],...>")
+		}
+
+		if _, err := interpreter.ParseQuirks(interpreter.Quirks{}, args[0]); err != nil {
+			return nil, err
+		}
+
+		return quirksStep{spec: args[0]}, nil
+	case "seed":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected: seed <number>")
+		}
+
+		seed, err := strconv.ParseInt(args[0], 0, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		return seedStep{seed: seed}, nil
+	case "press", "release":
+		return parseKeyStep(fields[0], args)
+	case "run":
+		if len(args) != 2 || (args[1] != "frames" && args[1] != "frame") {
+			return nil, fmt.Errorf("expected: run <n> frames")
+		}
+
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid number of frames %q", args[0])
+		}
+
+		return runStep{frames: n}, nil
+	case "expect":
+		return parseExpectStep(args)
+	}
+
+	return nil, fmt.Errorf("unknown step %q", fields[0])
+}
+
+// runner holds the state of a test while its steps run. The machine starts
+// with the first step that needs it, so load, profile, quirks and seed must
+// come before.
+type runner struct {
+	test        *Test
+	update      bool
+	programData []byte
+	platform    decoder.Platform
+	quirks      string
+	seed        int64
+	machine     *headless.Machine
+}
+
+func (r *runner) start() error {
+	if r.machine != nil {
+		return nil
+	}
+
+	if r.programData == nil {
+		return fmt.Errorf("no ROM loaded")
+	}
+
+	quirks, err := interpreter.ParseQuirks(interpreter.QuirksForProfile(r.platform), r.quirks)
+	if err != nil {
+		return err
+	}
+
+	m, err := headless.NewMachine(discard, quirks, r.seed, r.programData)
+	if err != nil {
+		return err
+	}
+
+	r.machine = m
+
+	return nil
+}
+
+func (r *runner) configure() error {
+	if r.machine != nil {
+		return fmt.Errorf("must come before the program starts running")
+	}
+
+	return nil
+}
+
+func (r *runner) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+
+	return filepath.Join(r.test.Dir, name)
+}
+
+type loadStep struct{ path string }
+
+func (s loadStep) String() string { return "load " + s.path }
+
+func (s loadStep) run(r *runner) error {
+	if err := r.configure(); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(r.path(s.path))
+	if err != nil {
+		return err
+	}
+
+	r.programData = data
+
+	return nil
+}
+
+type profileStep struct{ platform decoder.Platform }
+
+func (s profileStep) String() string { return "profile " + s.platform.String() }
+
+func (s profileStep) run(r *runner) error {
+	if err := r.configure(); err != nil {
+		return err
+	}
+
+	r.platform = s.platform
+
+	return nil
+}
+
+type quirksStep struct{ spec string }
+
+func (s quirksStep) String() string { return "quirks " + s.spec }
+
+func (s quirksStep) run(r *runner) error {
+	if err := r.configure(); err != nil {
+		return err
+	}
+
+	r.quirks = r.quirks + "," + s.spec
+
+	return nil
+}
+
+type seedStep struct{ seed int64 }
+
+func (s seedStep) String() string { return fmt.Sprintf("seed %d", s.seed) }
+
+func (s seedStep) run(r *runner) error {
+	if err := r.configure(); err != nil {
+		return err
+	}
+
+	r.seed = s.seed
+
+	return nil
+}
+
+// keyStep presses or releases a key now, or at the start of a later frame:
+// press 5, release A at frame 20
+type keyStep struct {
+	key     byte
+	frame   int // -1 for the current frame
+	pressed bool
+}
+
+func parseKeyStep(verb string, args []string) (Step, error) {
+	if len(args) != 1 && (len(args) != 4 || args[1] != "at" || args[2] != "frame") {
+		return nil, fmt.Errorf("expected: %s <key> [at frame <n>]", verb)
+	}
+
+	key, err := strconv.ParseUint(args[0], 16, 4)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key %q", args[0])
+	}
+
+	s := keyStep{key: byte(key), frame: -1, pressed: verb == "press"}
+
+	if len(args) == 4 {
+		if s.frame, err = strconv.Atoi(args[3]); err != nil || s.frame < 0 {
+			return nil, fmt.Errorf("invalid frame %q", args[3])
+		}
+	}
+
+	return s, nil
+}
+
+func (s keyStep) String() string {
+	verb := "release"
+	if s.pressed {
+		verb = "press"
+	}
+
+	if s.frame < 0 {
+		return fmt.Sprintf("%s %X", verb, s.key)
+	}
+
+	return fmt.Sprintf("%s %X at frame %d", verb, s.key, s.frame)
+}
+
+func (s keyStep) run(r *runner) error {
+	if err := r.start(); err != nil {
+		return err
+	}
+
+	frame := s.frame
+	if frame < 0 {
+		frame = r.machine.Frame()
+	}
+
+	if frame < r.machine.Frame() {
+		return fmt.Errorf("frame %d already ran, the machine is at frame %d", frame, r.machine.Frame())
+	}
+
+	r.machine.Schedule(headless.Event{Frame: frame, Key: s.key, Pressed: s.pressed})
+
+	return nil
+}
+
+type runStep struct{ frames int }
+
+func (s runStep) String() string { return fmt.Sprintf("run %d frames", s.frames) }
+
+func (s runStep) run(r *runner) error {
+	if err := r.start(); err != nil {
+		return err
+	}
+
+	return r.machine.RunFrames(s.frames)
+}
+
+// expectRegisterStep compares a register with a value:
+// expect V3 == 0x10, expect I >= 0x300
+type expectRegisterStep struct {
+	register string
+	op       string
+	value    int
+}
+
+// expectMemoryStep compares a range of memory, both ends included:
+// expect memory[0x300..0x302] == 1 2 3
+type expectMemoryStep struct {
+	from   int
+	to     int
+	values []byte
+}
+
+// expectScreenStep compares the screen with a golden file:
+// expect screen matches golden.txt
+type expectScreenStep struct{ golden string }
+
+func parseExpectStep(args []string) (Step, error) {
+	if len(args) == 3 && args[0] == "screen" && args[1] == "matches" {
+		return expectScreenStep{golden: args[2]}, nil
+	}
+
+	if len(args) < 3 {
+		return nil, fmt.Errorf("expected: expect <register|memory[...]> <op> <value> or expect screen matches <file>")
+	}
+
+	target, op, values := args[0], args[1], args[2:]
+
+	if strings.HasPrefix(target, "memory[") && strings.HasSuffix(target, "]") {
+		if op != "==" {
+			return nil, fmt.Errorf("memory can only be compared with ==")
+		}
+
+		return parseExpectMemory(strings.TrimSuffix(strings.TrimPrefix(target, "memory["), "]"), values)
+	}
+
+	if _, ok := registerValue(interpreter.State{}, target); !ok {
+		return nil, fmt.Errorf("unknown register %q", target)
+	}
+
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return nil, fmt.Errorf("unknown operator %q", op)
+	}
+
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected a single value, got %q", strings.Join(values, " "))
+	}
+
+	value, err := parseNumber(values[0], 16)
+	if err != nil {
+		return nil, err
+	}
+
+	return expectRegisterStep{register: strings.ToUpper(target), op: op, value: value}, nil
+}
+
+func parseExpectMemory(addresses string, values []string) (Step, error) {
+	from, to, isRange := strings.Cut(addresses, "..")
+	if !isRange {
+		to = from
+	}
+
+	s := expectMemoryStep{}
+	var err error
+
+	if s.from, err = parseNumber(from, 12); err != nil {
+		return nil, err
+	}
+
+	if s.to, err = parseNumber(to, 12); err != nil {
+		return nil, err
+	}
+
+	if s.to < s.from {
+		return nil, fmt.Errorf("memory range %s ends before it starts", addresses)
+	}
+
+	for _, field := range values {
+		for _, v := range strings.Split(field, ",") {
+			if v == "" {
+				continue
+			}
+
+			b, err := parseNumber(v, 8)
+			if err != nil {
+				return nil, err
+			}
+
+			s.values = append(s.values, byte(b))
+		}
+	}
+
+	if len(s.values) != s.to-s.from+1 {
+		return nil, fmt.Errorf("memory[%s] holds %d bytes, %d values given", addresses, s.to-s.from+1, len(s.values))
+	}
+
+	return s, nil
+}
+
+// parseNumber accepts decimal, 0x prefixed hexadecimal and 0b prefixed binary
+// numbers that fit in the given number of bits.
+func parseNumber(s string, bits int) (int, error) {
+	base := 10
+	digits := s
+
+	switch {
+	case strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X"):
+		base, digits = 16, s[2:]
+	case strings.HasPrefix(s, "0b") || strings.HasPrefix(s, "0B"):
+		base, digits = 2, s[2:]
+	}
+
+	n, err := strconv.ParseUint(digits, base, bits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %d bit number %q", bits, s)
+	}
+
+	return int(n), nil
+}
+
+func registerValue(state interpreter.State, name string) (int, bool) {
+	name = strings.ToUpper(name)
+
+	switch name {
+	case "I":
+		return int(state.I), true
+	case "PC":
+		return int(state.PC), true
+	case "SP":
+		return state.SP, true
+	case "DT":
+		return int(state.DelayTimer), true
+	case "ST":
+		return int(state.SoundTimer), true
+	}
+
+	if len(name) == 2 && name[0] == 'V' {
+		if i, err := strconv.ParseUint(name[1:], 16, 4); err == nil {
+			return int(state.Registers[i]), true
+		}
+	}
+
+	return 0, false
+}
+
+func (s expectRegisterStep) String() string {
+	return fmt.Sprintf("expect %s %s 0x%02X", s.register, s.op, s.value)
+}
+
+func (s expectRegisterStep) run(r *runner) error {
+	if err := r.start(); err != nil {
+		return err
+	}
+
+	got, _ := registerValue(r.machine.Chip8().State(), s.register)
+
+	ok := false
+	switch s.op {
+	case "==":
+		ok = got == s.value
+	case "!=":
+		ok = got != s.value
+	case "<":
+		ok = got < s.value
+	case "<=":
+		ok = got <= s.value
+	case ">":
+		ok = got > s.value
+	case ">=":
+		ok = got >= s.value
+	}
+
+	if !ok {
+		return fmt.Errorf("%s is 0x%02X (%d) at frame %d", s.register, got, got, r.machine.Frame())
+	}
+
+	return nil
+}
+
+func (s expectMemoryStep) String() string {
+	values := make([]string, len(s.values))
+	for i, v := range s.values {
+		values[i] = fmt.Sprintf("0x%02X", v)
+	}
+
+	return fmt.Sprintf("expect memory[0x%03X..0x%03X] == %s", s.from, s.to, strings.Join(values, " "))
+}
+
+func (s expectMemoryStep) run(r *runner) error {
+	if err := r.start(); err != nil {
+		return err
+	}
+
+	memory := r.machine.Chip8().State().Memory
+
+	if s.to >= len(memory) {
+		return fmt.Errorf("memory[0x%03X] is out of range", s.to)
+	}
+
+	got := memory[s.from : s.to+1]
+
+	for i, v := range s.values {
+		if got[i] != v {
+			return fmt.Errorf("memory[0x%03X] is 0x%02X, want 0x%02X (whole range: % X) at frame %d", s.from+i, got[i], v, got, r.machine.Frame())
+		}
+	}
+
+	return nil
+}
+
+func (s expectScreenStep) String() string { return "expect screen matches " + s.golden }
+
+func (s expectScreenStep) run(r *runner) error {
+	if err := r.start(); err != nil {
+		return err
+	}
+
+	screen := r.machine.Runtime().Framebuffer()
+	path := r.path(s.golden)
+
+	if r.update {
+		return os.WriteFile(path, screen.EncodeGolden(path), 0o644)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w (run with --update to create it)", err)
+	}
+
+	want, err := display.ReadGolden(path, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", s.golden, err)
+	}
+
+	if diff := display.Diff(want, screen); diff != "" {
+		return fmt.Errorf("screen at frame %d does not match %s:\n%s", r.machine.Frame(), s.golden, diff)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package selftest
+
+// program assembles a test ROM from raw opcodes. Jumps and calls can target
+// labels defined before or after them.
+type program struct {
+	words  []uint16
+	labels map[string]uint16
+	fixups map[int]string // word index -> label whose address goes in NNN
+}
+
+func newProgram() *program {
+	p := new(program)
+
+	p.labels = make(map[string]uint16)
+	p.fixups = make(map[int]string)
+
+	return p
+}
+
+// here returns the address of the next word.
+func (p *program) here() uint16 {
+	return uint16(0x200 + 2*len(p.words))
+}
+
+func (p *program) op(words ...uint16) *program {
+	p.words = append(p.words, words...)
+
+	return p
+}
+
+func (p *program) label(name string) *program {
+	p.labels[name] = p.here()
+
+	return p
+}
+
+// ref emits an NNN style opcode (1NNN, 2NNN, ANNN or BNNN) pointing at label.
+func (p *program) ref(opcode uint16, name string) *program {
+	p.fixups[len(p.words)] = name
+
+	return p.op(opcode)
+}
+
+// bytes emits raw data, padded to a whole word.
+func (p *program) bytes(data ...byte) *program {
+	if len(data)%2 != 0 {
+		data = append(data, 0x00)
+	}
+
+	for i := 0; i < len(data); i += 2 {
+		p.op(uint16(data[i])<<8 | uint16(data[i+1]))
+	}
+
+	return p
+}
+
+// show prints the given registers as 3 decimal digits each, 3 values per
+// row, and stops. Only V0 to V4, I and memory from 0xE00 are touched after
+// every register was saved, so the values shown are the ones the test left.
+func (p *program) show(regs ...byte) []byte {
+	p.op(0xAE00, 0xFF55) // save V0 - VF
+
+	for n, reg := range regs {
+		x := uint16(n%3) * 21
+		y := uint16(n/3) * 6
+
+		p.op(
+			0xAE00|uint16(reg), 0xF065, // V0 = saved register
+			0xAE80, 0xF033, // BCD of V0
+			0xAE80, 0xF265, // V0, V1, V2 = digits
+			0x6300|x, 0x6400|y,
+			0xF029, 0xD345, 0x7305,
+			0xF129, 0xD345, 0x7305,
+			0xF229, 0xD345,
+		)
+	}
+
+	p.label("halt").ref(0x1000, "halt")
+
+	return p.assemble()
+}
+
+func (p *program) assemble() []byte {
+	out := make([]byte, 0, 2*len(p.words))
+
+	for i, w := range p.words {
+		if name, ok := p.fixups[i]; ok {
+			addr, found := p.labels[name]
+			if !found {
+				panic("selftest: undefined label " + name)
+			}
+
+			w |= addr & 0x0FFF
+		}
+
+		out = append(out, byte(w>>8), byte(w))
+	}
+
+	return out
+}
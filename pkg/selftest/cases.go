@@ -0,0 +1,232 @@
+package selftest
+
+import (
+	_ "embed"
+
+	"github.com/otaviohenrique/zamorak/pkg/headless"
+)
+
+var (
+	//go:embed roms/test_opcode.ch8
+	testOpcodeROM []byte
+
+	//go:embed roms/ibm_logo.ch8
+	ibmLogoROM []byte
+)
+
+// Case is a test ROM and how to run it. Generated ROMs print the registers
+// that hold their results, bundled ROMs draw their own report.
+type Case struct {
+	Group  string // opcode, flags, quirks, keypad or rom
+	Name   string // opcode or quirk under test
+	ROM    []byte
+	Frames int
+	Events []headless.Event
+}
+
+// Key 5 held from the first frame until frame 3.
+var holdKey5 = []headless.Event{
+	{Frame: 0, Key: 0x5, Pressed: true},
+	{Frame: 3, Key: 0x5, Pressed: false},
+}
+
+// Cases returns every test the self test runs. The comment above a generated
+// ROM lists the values it prints, as chip8 / schip / xochip when they differ.
+func Cases() []Case {
+	return []Case{
+		// opcodes, written so that no quirk changes their outcome
+
+		// 66
+		opcode("00E0", newProgram().
+			op(0x6042, 0xA050, 0xD005, 0x00E0).show(0x0)),
+		// 1
+		opcode("1NNN", newProgram().
+			op(0x6501).ref(0x1000, "skip").op(0x6502).label("skip").show(0x5)),
+		// 17
+		opcode("2NNN", newProgram().
+			op(0x6500).ref(0x2000, "sub").op(0x7501).ref(0x1000, "end").
+			label("sub").op(0x7510, 0x00EE).
+			label("end").show(0x5)),
+		// 3
+		opcode("00EE", newProgram().
+			op(0x6500).ref(0x2000, "sub").ref(0x2000, "sub").ref(0x1000, "end").
+			label("sub").op(0x7501, 0x00EE).
+			label("end").op(0x7501).show(0x5)),
+		// 16
+		opcode("3XNN", newProgram().
+			op(0x6500, 0x6107, 0x3107, 0x7501, 0x3108, 0x7510).show(0x5)),
+		// 1
+		opcode("4XNN", newProgram().
+			op(0x6500, 0x6107, 0x4107, 0x7501, 0x4108, 0x7510).show(0x5)),
+		// 16
+		opcode("5XY0", newProgram().
+			op(0x6500, 0x6107, 0x6207, 0x6308, 0x5120, 0x7501, 0x5130, 0x7510).show(0x5)),
+		// 92
+		opcode("6XNN", newProgram().
+			op(0x6A5C).show(0xA)),
+		// 16, 51
+		opcode("7XNN", newProgram().
+			op(0x6AF0, 0x6F33, 0x7A20).show(0xA, 0xF)),
+		// 52
+		opcode("8XY0", newProgram().
+			op(0x6112, 0x6234, 0x8120).show(0x1)),
+		// 54
+		opcode("8XY1", newProgram().
+			op(0x6112, 0x6234, 0x8121).show(0x1)),
+		// 16
+		opcode("8XY2", newProgram().
+			op(0x6112, 0x6234, 0x8122).show(0x1)),
+		// 38
+		opcode("8XY3", newProgram().
+			op(0x6112, 0x6234, 0x8123).show(0x1)),
+		// 16, 1, 48, 0
+		opcode("8XY4", newProgram().
+			op(0x61F0, 0x6220, 0x8124, 0x86F0, 0x6310, 0x6420, 0x8344).show(0x1, 0x6, 0x3, 0xF)),
+		// 32, 1, 224, 0
+		opcode("8XY5", newProgram().
+			op(0x6130, 0x6210, 0x8125, 0x86F0, 0x6310, 0x6430, 0x8345).show(0x1, 0x6, 0x3, 0xF)),
+		// 2, 1
+		opcode("8XY6", newProgram().
+			op(0x6105, 0x6205, 0x8126).show(0x1, 0xF)),
+		// 32, 1, 224, 0
+		opcode("8XY7", newProgram().
+			op(0x6110, 0x6230, 0x8127, 0x86F0, 0x6330, 0x6410, 0x8347).show(0x1, 0x6, 0x3, 0xF)),
+		// 2, 1
+		opcode("8XYE", newProgram().
+			op(0x6181, 0x6281, 0x812E).show(0x1, 0xF)),
+		// 1
+		opcode("9XY0", newProgram().
+			op(0x6500, 0x6107, 0x6207, 0x6308, 0x9120, 0x7501, 0x9130, 0x7510).show(0x5)),
+		// 154
+		opcode("ANNN", newProgram().
+			ref(0xA000, "data").op(0xF065).ref(0x1000, "end").
+			label("data").bytes(0x9A).
+			label("end").show(0x0)),
+		opcode("BNNN", jumpProgram(0x04, 0x04)), // 2
+		// 0, 0
+		opcode("CXNN", newProgram().
+			op(0xC100, 0xC20F, 0x63F0, 0x8322).show(0x1, 0x3)),
+		// 0, 1
+		opcode("DXYN", newProgram().
+			op(0x600A, 0x610A, 0xA050, 0xD015, 0x86F0, 0xD015).show(0x6, 0xF)),
+		// 32
+		opcode("FX07", newProgram().
+			op(0x6120, 0xF115, 0xF207).show(0x2)),
+		// 1, only once the delay timer reached 0
+		opcode("FX15", newProgram().
+			op(0x6103, 0xF115, 0x6200).
+			label("wait").op(0xF307, 0x3300).ref(0x1000, "wait").op(0x6201).show(0x2)),
+		// 51
+		opcode("FX1E", newProgram().
+			ref(0xA000, "data").op(0x6102, 0xF11E, 0xF065).ref(0x1000, "end").
+			label("data").bytes(0x11, 0x22, 0x33, 0x44).
+			label("end").show(0x0)),
+		// 240, 144
+		opcode("FX29", newProgram().
+			op(0x610A, 0xF129, 0xF165).show(0x0, 0x1)),
+		// 1, 5, 6
+		opcode("FX33", newProgram().
+			op(0x619C).ref(0xA000, "data").op(0xF133).ref(0xA000, "data").op(0xF265).ref(0x1000, "end").
+			label("data").bytes(0x00, 0x00, 0x00).
+			label("end").show(0x0, 0x1, 0x2)),
+		// 1, 2, 3
+		opcode("FX55", newProgram().
+			op(0x6001, 0x6102, 0x6203).ref(0xA000, "data").op(0xF255, 0x6000, 0x6100, 0x6200).
+			ref(0xA000, "data").op(0xF265).ref(0x1000, "end").
+			label("data").bytes(0x00, 0x00, 0x00).
+			label("end").show(0x0, 0x1, 0x2)),
+		// 17, 34, 51
+		opcode("FX65", newProgram().
+			ref(0xA000, "data").op(0xF265).ref(0x1000, "end").
+			label("data").bytes(0x11, 0x22, 0x33).
+			label("end").show(0x0, 0x1, 0x2)),
+
+		// flags, VF must hold the flag even when it is also the target
+
+		// 1
+		flags("8XY4", newProgram().
+			op(0x6FF0, 0x6120, 0x8F14).show(0xF)),
+		// 1, 0, 1
+		flags("8XY5", newProgram().
+			op(0x6F30, 0x6110, 0x8F15, 0x86F0, 0x6105, 0x6205, 0x8125, 0x87F0).show(0x6, 0x1, 0x7)),
+		// 0 / 1 / 0
+		flags("8XY6", newProgram().
+			op(0x6F05, 0x6102, 0x8F16).show(0xF)),
+		// 1, 0, 1
+		flags("8XY7", newProgram().
+			op(0x6F10, 0x6130, 0x8F17, 0x86F0, 0x6105, 0x6205, 0x8127, 0x87F0).show(0x6, 0x1, 0x7)),
+		// 0 / 1 / 0
+		flags("8XYE", newProgram().
+			op(0x6F81, 0x6140, 0x8F1E).show(0xF)),
+
+		// quirks
+
+		// 0, 0, 0 / 85, 85, 85 / 85, 85, 85
+		quirk("vfreset", newProgram().
+			op(0x6101, 0x6202, 0x6F55, 0x8121, 0x86F0, 0x6F55, 0x8122, 0x87F0, 0x6F55, 0x8123).show(0x6, 0x7, 0xF)),
+		// 204, 34 / 17, 17 / 204, 34
+		quirk("memory", newProgram().
+			ref(0xA000, "store").op(0x6011, 0x6122, 0xF155, 0xF065).op(0x8500).
+			ref(0xA000, "load").op(0xF065, 0xF065).ref(0x1000, "end").
+			label("store").bytes(0xAA, 0xBB, 0xCC).
+			label("load").bytes(0x11, 0x22).
+			label("end").show(0x5, 0x0)),
+		// 2, 1, 32, 0 / 1, 0, 2, 1 / 2, 1, 32, 0
+		quirk("shifting", newProgram().
+			op(0x6102, 0x6205, 0x8126, 0x86F0, 0x6381, 0x6410, 0x834E).show(0x1, 0x6, 0x3, 0xF)),
+		quirk("jumping", jumpProgram(0x00, 0x04)), // 1 / 2 / 1
+		// 0 / 0 / 1
+		quirk("clipping", newProgram().
+			ref(0xA000, "pixel").op(0x6000, 0x6100, 0xD011).
+			ref(0xA000, "row").op(0x603C, 0xD011, 0x86F0, 0x00E0).ref(0x1000, "end").
+			label("pixel").bytes(0x80).
+			label("row").bytes(0xFF).
+			label("end").show(0x6)),
+		// 7 / 10 / 10
+		quirk("displaywait", newProgram().
+			op(0xA600, 0x6A0A, 0xFA15, 0xD001, 0xD001, 0xD001, 0xFB07).show(0xB)),
+
+		// keypad, key 5 is held for the first frames
+
+		// 0, 1
+		keypad("EX9E", newProgram().
+			op(0x6105, 0x6200, 0x6300, 0x6406, 0xE19E, 0x7201, 0xE49E, 0x7301).show(0x2, 0x3)),
+		// 1, 0
+		keypad("EXA1", newProgram().
+			op(0x6105, 0x6200, 0x6300, 0x6406, 0xE1A1, 0x7201, 0xE4A1, 0x7301).show(0x2, 0x3)),
+		// 5
+		keypad("FX0A", newProgram().
+			op(0x6200, 0xF20A).show(0x2)),
+
+		// bundled ROMs
+
+		{Group: "rom", Name: "test_opcode", ROM: testOpcodeROM, Frames: 120},
+		{Group: "rom", Name: "ibm_logo", ROM: ibmLogoROM, Frames: 120},
+	}
+}
+
+func opcode(name string, rom []byte) Case {
+	return Case{Group: "opcode", Name: name, ROM: rom, Frames: 60}
+}
+
+func flags(name string, rom []byte) Case {
+	return Case{Group: "flags", Name: name, ROM: rom, Frames: 60}
+}
+
+func quirk(name string, rom []byte) Case {
+	return Case{Group: "quirks", Name: name, ROM: rom, Frames: 60}
+}
+
+func keypad(name string, rom []byte) Case {
+	return Case{Group: "keypad", Name: name, ROM: rom, Frames: 60, Events: holdKey5}
+}
+
+// jumpProgram runs BNNN towards a target in page 2, so the jumping quirk adds
+// V2 instead of V0. Landing on the target sets V5 to 1, 4 bytes further to 2.
+func jumpProgram(v0, v2 byte) []byte {
+	return newProgram().
+		op(0x6000|uint16(v0), 0x6200|uint16(v2)).ref(0xB000, "target").
+		label("target").op(0x6501).ref(0x1000, "end").
+		op(0x6502).
+		label("end").show(0x5)
+}
@@ -0,0 +1,144 @@
+package selftest
+
+// GOLDEN holds the hash of the final screen of every case, per variant.
+// Regenerate with: zamorak selftest --write-golden pkg/selftest/golden.go
+var GOLDEN = map[string]uint64{
+	"opcode/00E0/chip8":         0xeda79a62d1035dea,
+	"opcode/00E0/schip":         0xeda79a62d1035dea,
+	"opcode/00E0/xochip":        0xeda79a62d1035dea,
+	"opcode/1NNN/chip8":         0xa8ff77b36dd7d205,
+	"opcode/1NNN/schip":         0xa8ff77b36dd7d205,
+	"opcode/1NNN/xochip":        0xa8ff77b36dd7d205,
+	"opcode/2NNN/chip8":         0x405653daeb113ca4,
+	"opcode/2NNN/schip":         0x405653daeb113ca4,
+	"opcode/2NNN/xochip":        0x405653daeb113ca4,
+	"opcode/00EE/chip8":         0xa6c4f3bab25200f5,
+	"opcode/00EE/schip":         0xa6c4f3bab25200f5,
+	"opcode/00EE/xochip":        0xa6c4f3bab25200f5,
+	"opcode/3XNN/chip8":         0xebbdd564f9fdd954,
+	"opcode/3XNN/schip":         0xebbdd564f9fdd954,
+	"opcode/3XNN/xochip":        0xebbdd564f9fdd954,
+	"opcode/4XNN/chip8":         0xa8ff77b36dd7d205,
+	"opcode/4XNN/schip":         0xa8ff77b36dd7d205,
+	"opcode/4XNN/xochip":        0xa8ff77b36dd7d205,
+	"opcode/5XY0/chip8":         0xebbdd564f9fdd954,
+	"opcode/5XY0/schip":         0xebbdd564f9fdd954,
+	"opcode/5XY0/xochip":        0xebbdd564f9fdd954,
+	"opcode/6XNN/chip8":         0x87762a1a5f2fe296,
+	"opcode/6XNN/schip":         0x87762a1a5f2fe296,
+	"opcode/6XNN/xochip":        0x87762a1a5f2fe296,
+	"opcode/7XNN/chip8":         0x0b6ca3f4b3139f90,
+	"opcode/7XNN/schip":         0x0b6ca3f4b3139f90,
+	"opcode/7XNN/xochip":        0x0b6ca3f4b3139f90,
+	"opcode/8XY0/chip8":         0x96ea058f61529b16,
+	"opcode/8XY0/schip":         0x96ea058f61529b16,
+	"opcode/8XY0/xochip":        0x96ea058f61529b16,
+	"opcode/8XY1/chip8":         0x53be073a1f7bb79a,
+	"opcode/8XY1/schip":         0x53be073a1f7bb79a,
+	"opcode/8XY1/xochip":        0x53be073a1f7bb79a,
+	"opcode/8XY2/chip8":         0xebbdd564f9fdd954,
+	"opcode/8XY2/schip":         0xebbdd564f9fdd954,
+	"opcode/8XY2/xochip":        0xebbdd564f9fdd954,
+	"opcode/8XY3/chip8":         0x556a3a111ba41e8e,
+	"opcode/8XY3/schip":         0x556a3a111ba41e8e,
+	"opcode/8XY3/xochip":        0x556a3a111ba41e8e,
+	"opcode/8XY4/chip8":         0xd219cdcc92a56534,
+	"opcode/8XY4/schip":         0xd219cdcc92a56534,
+	"opcode/8XY4/xochip":        0xd219cdcc92a56534,
+	"opcode/8XY5/chip8":         0x0554ae4490d0ac21,
+	"opcode/8XY5/schip":         0x0554ae4490d0ac21,
+	"opcode/8XY5/xochip":        0x0554ae4490d0ac21,
+	"opcode/8XY6/chip8":         0x06cd234a20d69f61,
+	"opcode/8XY6/schip":         0x06cd234a20d69f61,
+	"opcode/8XY6/xochip":        0x06cd234a20d69f61,
+	"opcode/8XY7/chip8":         0x0554ae4490d0ac21,
+	"opcode/8XY7/schip":         0x0554ae4490d0ac21,
+	"opcode/8XY7/xochip":        0x0554ae4490d0ac21,
+	"opcode/8XYE/chip8":         0x06cd234a20d69f61,
+	"opcode/8XYE/schip":         0x06cd234a20d69f61,
+	"opcode/8XYE/xochip":        0x06cd234a20d69f61,
+	"opcode/9XY0/chip8":         0xa8ff77b36dd7d205,
+	"opcode/9XY0/schip":         0xa8ff77b36dd7d205,
+	"opcode/9XY0/xochip":        0xa8ff77b36dd7d205,
+	"opcode/ANNN/chip8":         0x05277ac2e2e0a01a,
+	"opcode/ANNN/schip":         0x05277ac2e2e0a01a,
+	"opcode/ANNN/xochip":        0x05277ac2e2e0a01a,
+	"opcode/BNNN/chip8":         0x4522d1443fec01c1,
+	"opcode/BNNN/schip":         0x4522d1443fec01c1,
+	"opcode/BNNN/xochip":        0x4522d1443fec01c1,
+	"opcode/CXNN/chip8":         0xa0ff94012e58e232,
+	"opcode/CXNN/schip":         0xa0ff94012e58e232,
+	"opcode/CXNN/xochip":        0xa0ff94012e58e232,
+	"opcode/DXYN/chip8":         0xb21b75682c6bd8d5,
+	"opcode/DXYN/schip":         0xb21b75682c6bd8d5,
+	"opcode/DXYN/xochip":        0xb21b75682c6bd8d5,
+	"opcode/FX07/chip8":         0xbe1c312a89b009b2,
+	"opcode/FX07/schip":         0xbe1c312a89b009b2,
+	"opcode/FX07/xochip":        0xbe1c312a89b009b2,
+	"opcode/FX15/chip8":         0xa8ff77b36dd7d205,
+	"opcode/FX15/schip":         0xa8ff77b36dd7d205,
+	"opcode/FX15/xochip":        0xa8ff77b36dd7d205,
+	"opcode/FX1E/chip8":         0x2da758e87bc5b3ca,
+	"opcode/FX1E/schip":         0x2da758e87bc5b3ca,
+	"opcode/FX1E/xochip":        0x2da758e87bc5b3ca,
+	"opcode/FX29/chip8":         0x4535dd751600fcf4,
+	"opcode/FX29/schip":         0x4535dd751600fcf4,
+	"opcode/FX29/xochip":        0x4535dd751600fcf4,
+	"opcode/FX33/chip8":         0xcc870f0255a32b50,
+	"opcode/FX33/schip":         0xcc870f0255a32b50,
+	"opcode/FX33/xochip":        0xcc870f0255a32b50,
+	"opcode/FX55/chip8":         0x1db41da918b44c69,
+	"opcode/FX55/schip":         0x1db41da918b44c69,
+	"opcode/FX55/xochip":        0x1db41da918b44c69,
+	"opcode/FX65/chip8":         0x99f1fe7b4f54ae48,
+	"opcode/FX65/schip":         0x99f1fe7b4f54ae48,
+	"opcode/FX65/xochip":        0x99f1fe7b4f54ae48,
+	"flags/8XY4/chip8":          0xa8ff77b36dd7d205,
+	"flags/8XY4/schip":          0xa8ff77b36dd7d205,
+	"flags/8XY4/xochip":         0xa8ff77b36dd7d205,
+	"flags/8XY5/chip8":          0xe741d7f2acd22c0a,
+	"flags/8XY5/schip":          0xe741d7f2acd22c0a,
+	"flags/8XY5/xochip":         0xe741d7f2acd22c0a,
+	"flags/8XY6/chip8":          0xc980b6426a5c85ed,
+	"flags/8XY6/schip":          0xa8ff77b36dd7d205,
+	"flags/8XY6/xochip":         0xc980b6426a5c85ed,
+	"flags/8XY7/chip8":          0xe741d7f2acd22c0a,
+	"flags/8XY7/schip":          0xe741d7f2acd22c0a,
+	"flags/8XY7/xochip":         0xe741d7f2acd22c0a,
+	"flags/8XYE/chip8":          0xc980b6426a5c85ed,
+	"flags/8XYE/schip":          0xa8ff77b36dd7d205,
+	"flags/8XYE/xochip":         0xc980b6426a5c85ed,
+	"quirks/vfreset/chip8":      0x4b3eabc9f17192a8,
+	"quirks/vfreset/schip":      0x3a1a9107315237af,
+	"quirks/vfreset/xochip":     0x3a1a9107315237af,
+	"quirks/memory/chip8":       0x5f88fa3d27b8d682,
+	"quirks/memory/schip":       0x429c53a7f657af37,
+	"quirks/memory/xochip":      0x5f88fa3d27b8d682,
+	"quirks/shifting/chip8":     0x717e1e2bc2dc579c,
+	"quirks/shifting/schip":     0x0c0f5c7fe7768e9f,
+	"quirks/shifting/xochip":    0x717e1e2bc2dc579c,
+	"quirks/jumping/chip8":      0xa8ff77b36dd7d205,
+	"quirks/jumping/schip":      0x4522d1443fec01c1,
+	"quirks/jumping/xochip":     0xa8ff77b36dd7d205,
+	"quirks/clipping/chip8":     0xc980b6426a5c85ed,
+	"quirks/clipping/schip":     0xc980b6426a5c85ed,
+	"quirks/clipping/xochip":    0xa8ff77b36dd7d205,
+	"quirks/displaywait/chip8":  0xe91d372a6803fcd1,
+	"quirks/displaywait/schip":  0x3201257f51991d00,
+	"quirks/displaywait/xochip": 0x3201257f51991d00,
+	"keypad/EX9E/chip8":         0xb21b75682c6bd8d5,
+	"keypad/EX9E/schip":         0xb21b75682c6bd8d5,
+	"keypad/EX9E/xochip":        0xb21b75682c6bd8d5,
+	"keypad/EXA1/chip8":         0x829f27e8fd5d1942,
+	"keypad/EXA1/schip":         0x829f27e8fd5d1942,
+	"keypad/EXA1/xochip":        0x829f27e8fd5d1942,
+	"keypad/FX0A/chip8":         0x811dfbc93b66bac1,
+	"keypad/FX0A/schip":         0x811dfbc93b66bac1,
+	"keypad/FX0A/xochip":        0x811dfbc93b66bac1,
+	"rom/test_opcode/chip8":     0xf40fbf426a4577d7,
+	"rom/test_opcode/schip":     0xf40fbf426a4577d7,
+	"rom/test_opcode/xochip":    0xf40fbf426a4577d7,
+	"rom/ibm_logo/chip8":        0xb4a9f7038dc48f48,
+	"rom/ibm_logo/schip":        0xb4a9f7038dc48f48,
+	"rom/ibm_logo/xochip":       0xb4a9f7038dc48f48,
+}
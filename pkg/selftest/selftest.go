@@ -0,0 +1,109 @@
+package selftest
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"log/slog"
+
+	"github.com/otaviohenrique/zamorak/pkg/decoder"
+	"github.com/otaviohenrique/zamorak/pkg/display"
+	"github.com/otaviohenrique/zamorak/pkg/headless"
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+)
+
+var (
+	// Every case runs once per variant, with the quirks of its profile.
+	VARIANTS = []decoder.Platform{decoder.PlatformCHIP8, decoder.PlatformSCHIP, decoder.PlatformXOCHIP}
+
+	// Seed for CXNN, no case depends on the numbers it draws.
+	SEED int64 = 1
+)
+
+// Result is the outcome of one case on one variant.
+type Result struct {
+	Case    Case
+	Variant decoder.Platform
+	Screen  *display.Framebuffer
+	Hash    uint64
+	Golden  uint64
+	Err     error // the ROM could not be loaded or faulted
+}
+
+func (r Result) Passed() bool {
+	return r.Err == nil && r.Hash == r.Golden
+}
+
+// Key identifies the result in GOLDEN.
+func (r Result) Key() string {
+	return Key(r.Case, r.Variant)
+}
+
+func Key(c Case, variant decoder.Platform) string {
+	return c.Group + "/" + c.Name + "/" + variant.String()
+}
+
+// Run runs every case on every variant and compares the final screens with
+// the golden hashes.
+func Run(cases []Case) []Result {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	results := []Result{}
+
+	for _, c := range cases {
+		for _, variant := range VARIANTS {
+			results = append(results, runCase(log, c, variant))
+		}
+	}
+
+	return results
+}
+
+func runCase(log *slog.Logger, c Case, variant decoder.Platform) Result {
+	result := Result{Case: c, Variant: variant, Golden: GOLDEN[Key(c, variant)]}
+
+	m, err := headless.NewMachine(log, interpreter.QuirksForProfile(variant), SEED, c.ROM)
+	if err != nil {
+		result.Err = err
+
+		return result
+	}
+
+	m.Schedule(c.Events...)
+
+	if err := m.RunFrames(c.Frames); err != nil {
+		result.Err = err
+	}
+
+	result.Screen = m.Runtime().Framebuffer()
+	result.Hash = result.Screen.Hash()
+
+	return result
+}
+
+// WriteGolden writes the hashes of results as the source of GOLDEN, to
+// refresh golden.go after a change that is known to be right.
+func WriteGolden(w io.Writer, results []Result) error {
+	var b bytes.Buffer
+
+	fmt.Fprintln(&b, "package selftest")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "// GOLDEN holds the hash of the final screen of every case, per variant.")
+	fmt.Fprintln(&b, "// Regenerate with: zamorak selftest --write-golden pkg/selftest/golden.go")
+	fmt.Fprintln(&b, "var GOLDEN = map[string]uint64{")
+
+	for _, r := range results {
+		fmt.Fprintf(&b, "\t%q: 0x%016x,\n", r.Key(), r.Hash)
+	}
+
+	fmt.Fprintln(&b, "}")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(src)
+
+	return err
+}
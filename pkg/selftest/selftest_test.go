@@ -0,0 +1,17 @@
+package selftest
+
+import "testing"
+
+func TestBundledCasesPass(t *testing.T) {
+	for _, r := range Run(Cases()) {
+		if r.Passed() {
+			continue
+		}
+
+		if r.Err != nil {
+			t.Errorf("%s: %v", r.Key(), r.Err)
+		} else {
+			t.Errorf("%s: got %016x, want %016x\n%s", r.Key(), r.Hash, r.Golden, r.Screen)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package trace
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/otaviohenrique/zamorak/pkg/decoder"
+)
+
+var (
+	// Files start with the magic followed by a version byte.
+	BINARY_MAGIC   = []byte("Z8TR")
+	BINARY_VERSION = byte(1)
+
+	// cycle (8), pc (2), opcode (2), V0-VF (16), I (2), SP, DT and ST (1 each)
+	BINARY_RECORD_SIZE = 33
+)
+
+// BinaryWriter writes fixed size big endian records, about 33 bytes per
+// instruction. Mnemonics are not stored, they are decoded again on read.
+type BinaryWriter struct {
+	w      *bufio.Writer
+	header bool
+	buf    []byte
+}
+
+func NewBinaryWriter(w io.Writer) *BinaryWriter {
+	b := new(BinaryWriter)
+
+	b.w = bufio.NewWriter(w)
+	b.buf = make([]byte, BINARY_RECORD_SIZE)
+
+	return b
+}
+
+func (b *BinaryWriter) Write(r Record) error {
+	if !b.header {
+		b.header = true
+
+		if _, err := b.w.Write(append(append([]byte{}, BINARY_MAGIC...), BINARY_VERSION)); err != nil {
+			return err
+		}
+	}
+
+	binary.BigEndian.PutUint64(b.buf[0:], r.Cycle)
+	binary.BigEndian.PutUint16(b.buf[8:], r.PC)
+	binary.BigEndian.PutUint16(b.buf[10:], r.Opcode)
+	copy(b.buf[12:28], r.V[:])
+	binary.BigEndian.PutUint16(b.buf[28:], r.I)
+	b.buf[30] = r.SP
+	b.buf[31] = r.DT
+	b.buf[32] = r.ST
+
+	_, err := b.w.Write(b.buf)
+
+	return err
+}
+
+func (b *BinaryWriter) Flush() error {
+	return b.w.Flush()
+}
+
+type BinaryReader struct {
+	r   io.Reader
+	buf []byte
+}
+
+// NewBinaryReader checks the header and returns a reader for the records
+// that follow it.
+func NewBinaryReader(r io.Reader) (*BinaryReader, error) {
+	header := make([]byte, len(BINARY_MAGIC)+1)
+
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("reading trace header: %w", err)
+	}
+
+	if string(header[:len(BINARY_MAGIC)]) != string(BINARY_MAGIC) {
+		return nil, fmt.Errorf("not a binary trace")
+	}
+
+	if v := header[len(BINARY_MAGIC)]; v != BINARY_VERSION {
+		return nil, fmt.Errorf("unsupported trace version %d", v)
+	}
+
+	return &BinaryReader{r: r, buf: make([]byte, BINARY_RECORD_SIZE)}, nil
+}
+
+func (b *BinaryReader) Read() (Record, error) {
+	if _, err := io.ReadFull(b.r, b.buf); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return Record{}, fmt.Errorf("truncated trace record")
+		}
+
+		return Record{}, err
+	}
+
+	r := Record{
+		Cycle:  binary.BigEndian.Uint64(b.buf[0:]),
+		PC:     binary.BigEndian.Uint16(b.buf[8:]),
+		Opcode: binary.BigEndian.Uint16(b.buf[10:]),
+		I:      binary.BigEndian.Uint16(b.buf[28:]),
+		SP:     b.buf[30],
+		DT:     b.buf[31],
+		ST:     b.buf[32],
+		Fields: FieldAll,
+	}
+
+	copy(r.V[:], b.buf[12:28])
+	r.Mnemonic = decoder.Decode(byte(r.Opcode>>8), byte(r.Opcode)).Mnemonic()
+
+	return r, nil
+}
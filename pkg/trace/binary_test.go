@@ -0,0 +1,127 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	records := []Record{
+		{Cycle: 0, PC: 0x200, Opcode: 0x00E0, I: 0x000, Fields: FieldAll},
+		{Cycle: 1, PC: 0x202, Opcode: 0x6A2F, V: [16]byte{0: 1, 0xA: 0x2F, 0xF: 0xFF}, I: 0xFFF, SP: 3, DT: 0x10, ST: 0x20, Fields: FieldAll},
+		{Cycle: 1<<40 + 7, PC: 0xFFE, Opcode: 0xD015, Fields: FieldAll},
+	}
+
+	var buf bytes.Buffer
+
+	w := NewBinaryWriter(&buf)
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := len(BINARY_MAGIC) + 1 + len(records)*BINARY_RECORD_SIZE; buf.Len() != want {
+		t.Fatalf("trace is %d bytes, want %d", buf.Len(), want)
+	}
+
+	r, err := NewBinaryReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range records {
+		got, err := r.Read()
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+
+		// mnemonics are decoded on read
+		if got.Mnemonic == "" {
+			t.Errorf("record %d has no mnemonic", i)
+		}
+		got.Mnemonic = ""
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("record %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("read after the last record = %v, want io.EOF", err)
+	}
+}
+
+func TestBinaryLayout(t *testing.T) {
+	var buf bytes.Buffer
+
+	w := NewBinaryWriter(&buf)
+	w.Write(Record{
+		Cycle:  0x0102030405060708,
+		PC:     0x0234,
+		Opcode: 0xABCD,
+		V:      [16]byte{0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1A, 0x1B, 0x1C, 0x1D, 0x1E, 0x1F},
+		I:      0x0EEF,
+		SP:     0x05,
+		DT:     0x3C,
+		ST:     0x02,
+	})
+	w.Flush()
+
+	want := "5a38545201" + // Z8TR, version 1
+		"0102030405060708" + // cycle
+		"0234" + "abcd" + // pc, opcode
+		"101112131415161718191a1b1c1d1e1f" + // V0-VF
+		"0eef" + "05" + "3c" + "02" // I, SP, DT, ST
+
+	if got := hex.EncodeToString(buf.Bytes()); got != want {
+		t.Errorf("encoded\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestBinaryReaderErrors(t *testing.T) {
+	record := strings.Repeat("\x00", BINARY_RECORD_SIZE)
+
+	tests := []struct {
+		name   string
+		data   string
+		header string // error of NewBinaryReader
+		read   string // error of the first Read
+	}{
+		{name: "empty", data: "", header: "reading trace header"},
+		{name: "short header", data: "Z8T", header: "reading trace header"},
+		{name: "wrong magic", data: "Z8TX\x01", header: "not a binary trace"},
+		{name: "wrong version", data: "Z8TR\x02", header: "unsupported trace version 2"},
+		{name: "truncated record", data: "Z8TR\x01" + record[:10], read: "truncated trace record"},
+		{name: "no records", data: "Z8TR\x01", read: io.EOF.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewBinaryReader(strings.NewReader(tt.data))
+			if tt.header != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.header) {
+					t.Fatalf("NewBinaryReader error = %v, want %q", err, tt.header)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := r.Read(); err == nil || err.Error() != tt.read {
+				t.Errorf("Read error = %v, want %q", err, tt.read)
+			}
+		})
+	}
+}
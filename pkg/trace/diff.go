@@ -0,0 +1,108 @@
+package trace
+
+import (
+	"fmt"
+	"io"
+)
+
+// Pair is the record at the same position in both traces.
+type Pair struct {
+	A Record
+	B Record
+}
+
+// Divergence is the first position where two traces differ.
+type Divergence struct {
+	Index   int      // position of the record, from 0
+	A       *Record  // nil when the first trace ended before
+	B       *Record  // nil when the second trace ended before
+	Fields  []string // fields that differ
+	Context []Pair   // records right before the divergence, oldest first
+}
+
+// Diff reads both traces in lockstep and returns the first record where they
+// differ, with up to context records before it, or nil when they match.
+// Records are matched by position, cycle numbers are not compared, and only
+// fields present in both records are. It also returns how many records
+// matched.
+func Diff(a Reader, b Reader, context int) (*Divergence, int, error) {
+	history := []Pair{}
+
+	for index := 0; ; index++ {
+		ra, errA := a.Read()
+		if errA != nil && errA != io.EOF {
+			return nil, index, fmt.Errorf("first trace: %w", errA)
+		}
+
+		rb, errB := b.Read()
+		if errB != nil && errB != io.EOF {
+			return nil, index, fmt.Errorf("second trace: %w", errB)
+		}
+
+		if errA == io.EOF && errB == io.EOF {
+			return nil, index, nil
+		}
+
+		d := &Divergence{Index: index, Context: history}
+
+		switch {
+		case errA == io.EOF:
+			d.B = &rb
+			d.Fields = []string{"length"}
+		case errB == io.EOF:
+			d.A = &ra
+			d.Fields = []string{"length"}
+		default:
+			d.A, d.B = &ra, &rb
+			d.Fields = compare(ra, rb)
+		}
+
+		if len(d.Fields) > 0 {
+			return d, index, nil
+		}
+
+		history = append(history, Pair{A: ra, B: rb})
+		if len(history) > context {
+			history = history[1:]
+		}
+	}
+}
+
+func compare(a Record, b Record) []string {
+	fields := a.Fields & b.Fields
+	diff := []string{}
+
+	if fields&FieldPC != 0 && a.PC != b.PC {
+		diff = append(diff, "PC")
+	}
+
+	if fields&FieldOpcode != 0 && a.Opcode != b.Opcode {
+		diff = append(diff, "opcode")
+	}
+
+	if fields&FieldV != 0 {
+		for i := range a.V {
+			if a.V[i] != b.V[i] {
+				diff = append(diff, fmt.Sprintf("V%X", i))
+			}
+		}
+	}
+
+	if fields&FieldI != 0 && a.I != b.I {
+		diff = append(diff, "I")
+	}
+
+	if fields&FieldSP != 0 && a.SP != b.SP {
+		diff = append(diff, "SP")
+	}
+
+	if fields&FieldDT != 0 && a.DT != b.DT {
+		diff = append(diff, "DT")
+	}
+
+	if fields&FieldST != 0 && a.ST != b.ST {
+		diff = append(diff, "ST")
+	}
+
+	return diff
+}
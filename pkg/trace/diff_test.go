@@ -0,0 +1,140 @@
+package trace
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+// records is a Reader over a slice.
+type records []Record
+
+func (r *records) Read() (Record, error) {
+	if len(*r) == 0 {
+		return Record{}, io.EOF
+	}
+
+	next := (*r)[0]
+	*r = (*r)[1:]
+
+	return next, nil
+}
+
+func rec(cycle uint64, pc uint16) Record {
+	return Record{Cycle: cycle, PC: pc, Opcode: 0x7001, Fields: FieldAll}
+}
+
+func TestDiff(t *testing.T) {
+	changed := func(r Record, change func(r *Record)) Record {
+		change(&r)
+
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		a, b    []Record
+		context int
+		index   int      // -1 when the traces match
+		fields  []string // fields of the divergence
+		matched int
+		pairs   int // context pairs returned
+	}{
+		{
+			name:    "identical",
+			a:       []Record{rec(0, 0x200), rec(1, 0x202)},
+			b:       []Record{rec(0, 0x200), rec(1, 0x202)},
+			index:   -1,
+			matched: 2,
+		},
+		{
+			name:    "cycles are not compared",
+			a:       []Record{rec(0, 0x200)},
+			b:       []Record{rec(100, 0x200)},
+			index:   -1,
+			matched: 1,
+		},
+		{
+			name:    "pc",
+			a:       []Record{rec(0, 0x200), rec(1, 0x202), rec(2, 0x204)},
+			b:       []Record{rec(0, 0x200), rec(1, 0x202), rec(2, 0x206)},
+			context: 1,
+			index:   2,
+			fields:  []string{"PC"},
+			matched: 2,
+			pairs:   1,
+		},
+		{
+			name:    "registers",
+			a:       []Record{rec(0, 0x200)},
+			b:       []Record{changed(rec(0, 0x200), func(r *Record) { r.V[3], r.V[0xF], r.I = 1, 1, 0x300 })},
+			context: 5,
+			index:   0,
+			fields:  []string{"V3", "VF", "I"},
+		},
+		{
+			name:    "fields missing from one trace are not compared",
+			a:       []Record{changed(rec(0, 0x200), func(r *Record) { r.Fields = FieldPC; r.DT = 9 })},
+			b:       []Record{rec(0, 0x200)},
+			index:   -1,
+			matched: 1,
+		},
+		{
+			name:    "first trace shorter",
+			a:       []Record{rec(0, 0x200)},
+			b:       []Record{rec(0, 0x200), rec(1, 0x202)},
+			context: 5,
+			index:   1,
+			fields:  []string{"length"},
+			matched: 1,
+			pairs:   1,
+		},
+		{
+			name:    "second trace shorter",
+			a:       []Record{rec(0, 0x200), rec(1, 0x202)},
+			b:       []Record{rec(0, 0x200)},
+			index:   1,
+			fields:  []string{"length"},
+			matched: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := records(tt.a), records(tt.b)
+
+			d, matched, err := Diff(&a, &b, tt.context)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if matched != tt.matched {
+				t.Errorf("matched %d records, want %d", matched, tt.matched)
+			}
+
+			if tt.index < 0 {
+				if d != nil {
+					t.Errorf("diverged at %d on %v, want no divergence", d.Index, d.Fields)
+				}
+
+				return
+			}
+
+			if d == nil {
+				t.Fatalf("no divergence, want one at %d", tt.index)
+			}
+
+			if d.Index != tt.index || !reflect.DeepEqual(d.Fields, tt.fields) {
+				t.Errorf("diverged at %d on %v, want %d on %v", d.Index, d.Fields, tt.index, tt.fields)
+			}
+
+			if len(d.Context) != tt.pairs {
+				t.Errorf("%d context records, want %d", len(d.Context), tt.pairs)
+			}
+
+			if (d.A == nil) != (tt.index >= len(tt.a)) || (d.B == nil) != (tt.index >= len(tt.b)) {
+				t.Errorf("records A=%v B=%v, want nil for the trace that ended", d.A, d.B)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/otaviohenrique/zamorak/pkg/decoder"
+)
+
+// jsonRecord is one line of a JSON lines trace:
+//
+//	{"cycle":0,"pc":512,"opcode":224,"mnemonic":"CLS","v":[0,...],"i":0,"sp":0,"dt":0,"st":0}
+//
+// Every field but cycle may be left out by logs converted from other
+// emulators, missing fields are not compared.
+type jsonRecord struct {
+	Cycle    uint64  `json:"cycle"`
+	PC       *uint16 `json:"pc,omitempty"`
+	Opcode   *uint16 `json:"opcode,omitempty"`
+	Mnemonic string  `json:"mnemonic,omitempty"`
+	V        []int   `json:"v,omitempty"`
+	I        *uint16 `json:"i,omitempty"`
+	SP       *byte   `json:"sp,omitempty"`
+	DT       *byte   `json:"dt,omitempty"`
+	ST       *byte   `json:"st,omitempty"`
+}
+
+type JSONWriter struct {
+	w *bufio.Writer
+}
+
+func NewJSONWriter(w io.Writer) *JSONWriter {
+	return &JSONWriter{w: bufio.NewWriter(w)}
+}
+
+func (j *JSONWriter) Write(r Record) error {
+	v := make([]int, len(r.V))
+	for i, b := range r.V {
+		v[i] = int(b)
+	}
+
+	line, err := json.Marshal(jsonRecord{
+		Cycle:    r.Cycle,
+		PC:       &r.PC,
+		Opcode:   &r.Opcode,
+		Mnemonic: r.Mnemonic,
+		V:        v,
+		I:        &r.I,
+		SP:       &r.SP,
+		DT:       &r.DT,
+		ST:       &r.ST,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := j.w.Write(line); err != nil {
+		return err
+	}
+
+	return j.w.WriteByte('\n')
+}
+
+func (j *JSONWriter) Flush() error {
+	return j.w.Flush()
+}
+
+type JSONReader struct {
+	dec  *json.Decoder
+	line int
+}
+
+func NewJSONReader(r io.Reader) *JSONReader {
+	return &JSONReader{dec: json.NewDecoder(r)}
+}
+
+func (j *JSONReader) Read() (Record, error) {
+	var raw jsonRecord
+
+	j.line++
+
+	if err := j.dec.Decode(&raw); err != nil {
+		if err == io.EOF {
+			return Record{}, err
+		}
+
+		return Record{}, fmt.Errorf("trace record %d: %w", j.line, err)
+	}
+
+	r := Record{Cycle: raw.Cycle, Mnemonic: raw.Mnemonic}
+
+	if raw.PC != nil {
+		r.PC = *raw.PC
+		r.Fields |= FieldPC
+	}
+
+	if raw.Opcode != nil {
+		r.Opcode = *raw.Opcode
+		r.Fields |= FieldOpcode
+
+		if r.Mnemonic == "" {
+			r.Mnemonic = decoder.Decode(byte(r.Opcode>>8), byte(r.Opcode)).Mnemonic()
+		}
+	}
+
+	if raw.V != nil {
+		if len(raw.V) != 16 {
+			return Record{}, fmt.Errorf("trace record %d: v has %d registers, expected 16", j.line, len(raw.V))
+		}
+
+		for i, v := range raw.V {
+			r.V[i] = byte(v)
+		}
+
+		r.Fields |= FieldV
+	}
+
+	if raw.I != nil {
+		r.I = *raw.I
+		r.Fields |= FieldI
+	}
+
+	if raw.SP != nil {
+		r.SP = *raw.SP
+		r.Fields |= FieldSP
+	}
+
+	if raw.DT != nil {
+		r.DT = *raw.DT
+		r.Fields |= FieldDT
+	}
+
+	if raw.ST != nil {
+		r.ST = *raw.ST
+		r.Fields |= FieldST
+	}
+
+	return r, nil
+}
@@ -0,0 +1,179 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/otaviohenrique/zamorak/pkg/decoder"
+	"github.com/otaviohenrique/zamorak/pkg/interpreter"
+)
+
+// Field is a bit set of the parts of a record a log provides. Traces written
+// by zamorak have every field, logs converted from other emulators may not.
+type Field uint16
+
+const (
+	FieldPC Field = 1 << iota
+	FieldOpcode
+	FieldV
+	FieldI
+	FieldSP
+	FieldDT
+	FieldST
+
+	FieldAll = FieldPC | FieldOpcode | FieldV | FieldI | FieldSP | FieldDT | FieldST
+)
+
+// Record is the machine state right before an instruction executes.
+type Record struct {
+	Cycle    uint64 // instructions executed before this one
+	PC       uint16
+	Opcode   uint16
+	Mnemonic string
+	V        [16]byte
+	I        uint16
+	SP       byte // stack depth
+	DT       byte
+	ST       byte
+	Fields   Field
+}
+
+func (r Record) String() string {
+	return fmt.Sprintf("#%-8d %03X %04X %-16s V=% X I=%03X SP=%d DT=%02X ST=%02X",
+		r.Cycle, r.PC, r.Opcode, r.Mnemonic, r.V[:], r.I, r.SP, r.DT, r.ST)
+}
+
+// Writer stores records in one of the trace formats.
+type Writer interface {
+	Write(r Record) error
+	Flush() error
+}
+
+// Reader reads records back, returning io.EOF after the last one.
+type Reader interface {
+	Read() (Record, error)
+}
+
+// Tracer records every instruction a Chip8 executes. Install Hook with
+// Chip8.AddHook.
+type Tracer struct {
+	mu     sync.Mutex
+	writer Writer
+	closer io.Closer
+	cycle  uint64
+	err    error
+}
+
+func NewTracer(w Writer) *Tracer {
+	t := new(Tracer)
+
+	t.writer = w
+
+	return t
+}
+
+// Create opens a trace file, in JSON lines when the name ends in .jsonl or
+// .json and in the binary format otherwise.
+func Create(path string) (*Tracer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var w Writer
+	if strings.HasSuffix(path, ".jsonl") || strings.HasSuffix(path, ".json") {
+		w = NewJSONWriter(f)
+	} else {
+		w = NewBinaryWriter(f)
+	}
+
+	t := NewTracer(w)
+	t.closer = f
+
+	return t, nil
+}
+
+// Hook is an interpreter.Hook that writes a record per instruction. The
+// first write error stops tracing and is returned by Close.
+func (t *Tracer) Hook(c *interpreter.Chip8, pc uint16, instr decoder.Instruction) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.err != nil || t.writer == nil {
+		return
+	}
+
+	t.err = t.writer.Write(Capture(c, t.cycle, pc, instr))
+	t.cycle++
+}
+
+// Close flushes the trace and closes the file it was created with. Later
+// instructions are not recorded.
+func (t *Tracer) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.writer == nil {
+		return t.err
+	}
+
+	if err := t.writer.Flush(); t.err == nil {
+		t.err = err
+	}
+
+	if t.closer != nil {
+		if err := t.closer.Close(); t.err == nil {
+			t.err = err
+		}
+	}
+
+	t.writer = nil
+
+	return t.err
+}
+
+// Capture builds the record of an instruction about to run.
+func Capture(c *interpreter.Chip8, cycle uint64, pc uint16, instr decoder.Instruction) Record {
+	delay, sound := c.Timers()
+
+	return Record{
+		Cycle:    cycle,
+		PC:       pc,
+		Opcode:   instr.Opcode,
+		Mnemonic: instr.Mnemonic(),
+		V:        c.Registers(),
+		I:        c.Index(),
+		SP:       byte(c.StackDepth()),
+		DT:       delay,
+		ST:       sound,
+		Fields:   FieldAll,
+	}
+}
+
+// Open reads a trace file in either format.
+func Open(path string) (Reader, io.Closer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(f)
+
+	magic, err := br.Peek(len(BINARY_MAGIC))
+	if err == nil && bytes.Equal(magic, BINARY_MAGIC) {
+		r, err := NewBinaryReader(br)
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		return r, f, nil
+	}
+
+	return NewJSONReader(br), f, nil
+}
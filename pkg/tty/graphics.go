@@ -0,0 +1,219 @@
+package tty
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/otaviohenrique/zamorak/pkg/display"
+	"github.com/otaviohenrique/zamorak/pkg/palette"
+)
+
+var (
+	// graphics protocols drawing the screen with real pixels
+	GRAPHICS = []string{"sixel", "kitty"}
+
+	// how long the terminal has to answer the capability query before it is
+	// taken as not supporting graphics
+	DETECT_TIMEOUT = time.Second
+
+	// character size, in pixels, assumed when the terminal does not report
+	// its size in pixels
+	DEFAULT_CELL_WIDTH  = 8
+	DEFAULT_CELL_HEIGHT = 16
+
+	// base64 bytes sent per escape sequence with the kitty protocol
+	KITTY_CHUNK_SIZE = 4096
+)
+
+const (
+	// id of the image the screen is drawn to with the kitty protocol, sent
+	// again every frame to replace the previous one
+	kittyImage = 1
+
+	// id of the image queried to detect the kitty protocol
+	kittyQuery = 31
+
+	// attribute a terminal reports in its primary device attributes when it
+	// draws sixels
+	sixelAttribute = "4"
+)
+
+// query returns what is written to the terminal to ask whether it supports a
+// graphics protocol. Every query ends with a request of the primary device
+// attributes, which all terminals answer, so a missing answer to the rest can
+// be told apart from a slow terminal.
+func query(protocol string) string {
+	const da1 = "\x1b[c"
+
+	if protocol == "kitty" {
+		// a 1x1 RGB image the terminal only checks and does not keep
+		return fmt.Sprintf("\x1b_Gi=%d,s=1,v=1,a=q,t=d,f=24;AAAA\x1b\\", kittyQuery) + da1
+	}
+
+	return da1
+}
+
+// answered looks for the device attributes in what the terminal replied to a
+// query and reports whether the terminal supports the protocol. done is
+// false until the device attributes arrive; rest is what was read after
+// them, keys typed in the meantime.
+func answered(protocol string, reply []byte) (supported bool, done bool, rest []byte) {
+	start := bytes.Index(reply, []byte("\x1b[?"))
+	if start < 0 {
+		return false, false, nil
+	}
+
+	end := bytes.IndexByte(reply[start:], 'c')
+	if end < 0 {
+		return false, false, nil
+	}
+	end += start
+
+	rest = reply[end+1:]
+
+	if protocol == "kitty" {
+		ok := fmt.Sprintf("\x1b_Gi=%d;OK", kittyQuery)
+
+		return bytes.Contains(reply[:start], []byte(ok)), true, rest
+	}
+
+	for _, attr := range strings.Split(string(reply[start+3:end]), ";") {
+		if attr == sixelAttribute {
+			return true, true, rest
+		}
+	}
+
+	return false, true, rest
+}
+
+// frame draws a framebuffer in the colors of a palette, every pixel scale x
+// scale times larger, with the background at index 0 and lit pixels at 1.
+func frame(fb *display.Framebuffer, p palette.Palette, scale int) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, fb.Width()*scale, fb.Height()*scale), color.Palette{p.Background(), p.Color(1)})
+
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			if fb.IsPixelSet(x/scale, y/scale) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+
+	return img
+}
+
+// sixel encodes an image as a sixel sequence. Every band of 6 rows is drawn
+// once per color, with runs of the same column pattern compressed.
+func sixel(img *image.Paletted) string {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+
+	var b strings.Builder
+
+	// 1:1 pixel aspect, with the size of the image
+	fmt.Fprintf(&b, "\x1bP0;1;0q\"1;1;%d;%d", w, h)
+
+	for i, c := range img.Palette {
+		r, g, bl, _ := c.RGBA()
+
+		// sixel colors are given in percent
+		fmt.Fprintf(&b, "#%d;2;%d;%d;%d", i, r*100/0xFFFF, g*100/0xFFFF, bl*100/0xFFFF)
+	}
+
+	pattern := make([]byte, w)
+
+	for band := 0; band < h; band += 6 {
+		for i := range img.Palette {
+			used := false
+
+			for x := 0; x < w; x++ {
+				bits := byte(0)
+
+				for dy := 0; dy < 6 && band+dy < h; dy++ {
+					if int(img.ColorIndexAt(x, band+dy)) == i {
+						bits |= 1 << dy
+					}
+				}
+
+				pattern[x] = '?' + bits
+				used = used || bits != 0
+			}
+
+			if !used {
+				continue
+			}
+
+			fmt.Fprintf(&b, "#%d", i)
+
+			for x := 0; x < w; {
+				run := 1
+				for x+run < w && pattern[x+run] == pattern[x] {
+					run++
+				}
+
+				if run > 3 {
+					b.WriteString("!" + strconv.Itoa(run))
+					b.WriteByte(pattern[x])
+				} else {
+					b.Write(pattern[x : x+run])
+				}
+
+				x += run
+			}
+
+			// back to the start of the band for the next color
+			b.WriteByte('$')
+		}
+
+		b.WriteByte('-')
+	}
+
+	b.WriteString("\x1b\\")
+
+	return b.String()
+}
+
+// kitty encodes an image as a PNG sent with the kitty graphics protocol,
+// replacing the image drawn the frame before and leaving the cursor where it
+// is.
+func kitty(img *image.Paletted) (string, error) {
+	var data bytes.Buffer
+	if err := png.Encode(&data, img); err != nil {
+		return "", err
+	}
+
+	payload := base64.StdEncoding.EncodeToString(data.Bytes())
+
+	var b strings.Builder
+
+	for i := 0; i < len(payload); i += KITTY_CHUNK_SIZE {
+		chunk := payload[i:min(i+KITTY_CHUNK_SIZE, len(payload))]
+
+		more := 0
+		if i+KITTY_CHUNK_SIZE < len(payload) {
+			more = 1
+		}
+
+		// the first chunk holds the controls, the others only say whether
+		// more follow
+		if i == 0 {
+			fmt.Fprintf(&b, "\x1b_Ga=T,f=100,i=%d,q=2,C=1,m=%d;%s\x1b\\", kittyImage, more, chunk)
+		} else {
+			fmt.Fprintf(&b, "\x1b_Gm=%d;%s\x1b\\", more, chunk)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// fitPixels returns the largest whole scale such that a w x h screen fits in
+// width x height pixels, at least 1.
+func fitPixels(w, h, width, height int) int {
+	return max(min(width/w, height/h), 1)
+}
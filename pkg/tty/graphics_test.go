@@ -0,0 +1,166 @@
+package tty
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/otaviohenrique/zamorak/pkg/palette"
+)
+
+func TestAnswered(t *testing.T) {
+	kittyOK := "\x1b_Gi=31;OK\x1b\\"
+
+	tests := []struct {
+		name      string
+		protocol  string
+		reply     string
+		supported bool
+		done      bool
+		rest      string
+	}{
+		{"nothing yet", "sixel", "", false, false, ""},
+		{"attributes cut short", "sixel", "\x1b[?62;4", false, false, ""},
+		{"sixel", "sixel", "\x1b[?62;4;22c", true, true, ""},
+		{"no sixel", "sixel", "\x1b[?62;22c", false, true, ""},
+		{"attribute 4 only as a prefix", "sixel", "\x1b[?62;42c", false, true, ""},
+		{"keys typed after", "sixel", "\x1b[?4cqw", true, true, "qw"},
+		{"kitty", "kitty", kittyOK + "\x1b[?62;22c", true, true, ""},
+		{"kitty error", "kitty", "\x1b_Gi=31;ENOTSUPPORTED\x1b\\\x1b[?62c", false, true, ""},
+		{"no kitty answer", "kitty", "\x1b[?62;4c", false, true, ""},
+		{"kitty answer still coming", "kitty", kittyOK, false, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			supported, done, rest := answered(tt.protocol, []byte(tt.reply))
+			if supported != tt.supported || done != tt.done || string(rest) != tt.rest {
+				t.Errorf("answered = %v, %v, %q, want %v, %v, %q", supported, done, rest, tt.supported, tt.done, tt.rest)
+			}
+		})
+	}
+}
+
+func TestFrame(t *testing.T) {
+	fb := screen(
+		"#.",
+		".#",
+	)
+
+	img := frame(fb, palette.PRESETS["classic"], 2)
+
+	if img.Rect.Dx() != 4 || img.Rect.Dy() != 4 {
+		t.Fatalf("image is %v, want 4x4", img.Rect)
+	}
+
+	want := []uint8{
+		1, 1, 0, 0,
+		1, 1, 0, 0,
+		0, 0, 1, 1,
+		0, 0, 1, 1,
+	}
+	if !bytes.Equal(img.Pix, want) {
+		t.Errorf("pixels = %v, want %v", img.Pix, want)
+	}
+}
+
+func TestSixel(t *testing.T) {
+	// 8x2 pixels: the top row lit, then the first 5 columns of the bottom
+	fb := screen(
+		"########",
+		"#####...",
+	)
+
+	got := sixel(frame(fb, palette.PRESETS["classic"], 1))
+
+	want := "\x1bP0;1;0q\"1;1;8;2" +
+		"#0;2;0;0;0#1;2;100;100;100" +
+		// background: nothing for 5 columns, then the second row, then lit:
+		// both rows for 5 columns, the first row for 3
+		"#0!5?AAA$" +
+		"#1!5B@@@$" +
+		"-\x1b\\"
+
+	if got != want {
+		t.Errorf("sixel = %q, want %q", got, want)
+	}
+}
+
+func TestKitty(t *testing.T) {
+	fb := screen(
+		"#.",
+		".#",
+	)
+
+	old := KITTY_CHUNK_SIZE
+	KITTY_CHUNK_SIZE = 16
+	defer func() { KITTY_CHUNK_SIZE = old }()
+
+	got, err := kitty(frame(fb, palette.PRESETS["classic"], 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunks := regexp.MustCompile("\x1b_G([^;]*);([^\x1b]*)\x1b\\\\").FindAllStringSubmatch(got, -1)
+	if len(chunks) < 2 {
+		t.Fatalf("kitty sent %d chunks, want several", len(chunks))
+	}
+
+	var payload strings.Builder
+
+	for i, ch := range chunks {
+		controls, data := ch[1], ch[2]
+
+		if i == 0 && !strings.HasPrefix(controls, "a=T,f=100,i=1,") {
+			t.Errorf("first chunk controls = %q, want the image controls", controls)
+		}
+
+		more := "m=1"
+		if i == len(chunks)-1 {
+			more = "m=0"
+		}
+		if !strings.HasSuffix(controls, more) {
+			t.Errorf("chunk %d controls = %q, want %s", i, controls, more)
+		}
+
+		if len(data) > KITTY_CHUNK_SIZE {
+			t.Errorf("chunk %d holds %d bytes, want at most %d", i, len(data), KITTY_CHUNK_SIZE)
+		}
+
+		payload.WriteString(data)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if img.Bounds().Dx() != 2 || img.Bounds().Dy() != 2 {
+		t.Errorf("image is %v, want 2x2", img.Bounds())
+	}
+}
+
+func TestFitPixels(t *testing.T) {
+	tests := []struct {
+		width, height int
+		want          int
+	}{
+		{640, 384, 10},
+		{640, 100, 3},
+		{10, 10, 1},
+	}
+
+	for _, tt := range tests {
+		if got := fitPixels(64, 32, tt.width, tt.height); got != tt.want {
+			t.Errorf("fitPixels(%dx%d) = %d, want %d", tt.width, tt.height, got, tt.want)
+		}
+	}
+}
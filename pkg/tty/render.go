@@ -0,0 +1,147 @@
+package tty
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/otaviohenrique/zamorak/pkg/display"
+	"github.com/otaviohenrique/zamorak/pkg/palette"
+)
+
+var (
+	// ways of drawing pixels with characters: half blocks fit 1x2 pixels in
+	// a character, braille 2x4
+	RENDERERS = []string{"halfblock", "braille"}
+)
+
+// cell is how many pixels a character holds for a renderer.
+func cell(renderer string) (int, int) {
+	if renderer == "braille" {
+		return 2, 4
+	}
+
+	return 1, 2
+}
+
+// fit returns the largest whole number of characters per pixel block such
+// that a w x h screen fits in cols x rows characters, at least 1.
+func fit(renderer string, w, h, cols, rows int) int {
+	cw, ch := cell(renderer)
+
+	scale := min(cols*cw/w, rows*ch/h)
+
+	return max(scale, 1)
+}
+
+// render draws a framebuffer scaled by scale with characters, each line
+// starting pad columns in. With color, pixels take the colors of the
+// palette, in 24 bit color, otherwise the colors of the terminal.
+func render(fb *display.Framebuffer, renderer string, scale int, pad int, p palette.Palette, useColor bool) []string {
+	cw, ch := cell(renderer)
+	cols := (fb.Width()*scale + cw - 1) / cw
+	rows := (fb.Height()*scale + ch - 1) / ch
+
+	lit := func(x, y int) bool {
+		x, y = x/scale, y/scale
+
+		return x < fb.Width() && y < fb.Height() && fb.IsPixelSet(x, y)
+	}
+
+	lines := make([]string, rows)
+
+	for row := 0; row < rows; row++ {
+		var b strings.Builder
+		var lastFg, lastBg string
+
+		b.WriteString(strings.Repeat(" ", pad))
+
+		if useColor && renderer == "braille" {
+			b.WriteString(fg(p.Color(1)) + bg(p.Background()))
+		}
+
+		for col := 0; col < cols; col++ {
+			x, y := col*cw, row*ch
+
+			if renderer == "braille" {
+				b.WriteRune(braille(x, y, lit))
+
+				continue
+			}
+
+			top, bottom := lit(x, y), lit(x, y+1)
+
+			if useColor {
+				// colors are only set when they change from the last character
+				if f := fg(pixelColor(p, top)); f != lastFg {
+					b.WriteString(f)
+					lastFg = f
+				}
+
+				if k := bg(pixelColor(p, bottom)); k != lastBg {
+					b.WriteString(k)
+					lastBg = k
+				}
+
+				b.WriteString("▀")
+
+				continue
+			}
+
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+
+		if useColor {
+			b.WriteString("\x1b[0m")
+		}
+
+		lines[row] = b.String()
+	}
+
+	return lines
+}
+
+// braille returns the braille character of the 2x4 pixels at x, y.
+func braille(x, y int, lit func(x, y int) bool) rune {
+	// dots of a braille character, column by column
+	dots := [2][4]rune{
+		{0x01, 0x02, 0x04, 0x40},
+		{0x08, 0x10, 0x20, 0x80},
+	}
+
+	r := rune(0x2800)
+	for dx := 0; dx < 2; dx++ {
+		for dy := 0; dy < 4; dy++ {
+			if lit(x+dx, y+dy) {
+				r |= dots[dx][dy]
+			}
+		}
+	}
+
+	return r
+}
+
+func pixelColor(p palette.Palette, on bool) color.RGBA {
+	if on {
+		return p.Color(1)
+	}
+
+	return p.Background()
+}
+
+func fg(c color.RGBA) string {
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", c.R, c.G, c.B)
+}
+
+func bg(c color.RGBA) string {
+	return fmt.Sprintf("\x1b[48;2;%d;%d;%dm", c.R, c.G, c.B)
+}
@@ -0,0 +1,131 @@
+package tty
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/otaviohenrique/zamorak/pkg/display"
+	"github.com/otaviohenrique/zamorak/pkg/palette"
+)
+
+// screen returns a framebuffer with the pixels marked # lit.
+func screen(rows ...string) *display.Framebuffer {
+	fb := display.NewFramebuffer(len(rows[0]), len(rows))
+
+	for y, row := range rows {
+		for x, c := range row {
+			fb.Set(x, y, c == '#')
+		}
+	}
+
+	return fb
+}
+
+func TestRenderHalfblock(t *testing.T) {
+	fb := screen(
+		"#.#.",
+		"##..",
+	)
+
+	got := render(fb, "halfblock", 1, 2, palette.PRESETS["classic"], false)
+	if want := []string{"  █▄▀ "}; !reflect.DeepEqual(got, want) {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+
+	// every pixel twice as large
+	got = render(fb, "halfblock", 2, 0, palette.PRESETS["classic"], false)
+	if want := []string{"██  ██  ", "████    "}; !reflect.DeepEqual(got, want) {
+		t.Errorf("render scaled = %q, want %q", got, want)
+	}
+}
+
+func TestRenderHalfblockColor(t *testing.T) {
+	fb := screen(
+		"##",
+		"#.",
+	)
+
+	p := palette.PRESETS["amber"]
+	on, off := p.Color(1), p.Background()
+
+	got := render(fb, "halfblock", 1, 0, p, true)
+
+	// the foreground does not change, so it is set once
+	want := fg(on) + bg(on) + "▀" + bg(off) + "▀" + "\x1b[0m"
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+}
+
+func TestRenderBraille(t *testing.T) {
+	fb := screen(
+		"#..#",
+		".#..",
+		"..#.",
+		"...#",
+	)
+
+	got := render(fb, "braille", 1, 0, palette.PRESETS["classic"], false)
+
+	// dots 1 and 5, then 3, 4 and 8
+	if want := []string{"⠑⢌"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+}
+
+func TestBraille(t *testing.T) {
+	all := func(x, y int) bool { return true }
+	none := func(x, y int) bool { return false }
+
+	if r := braille(0, 0, all); r != '⣿' {
+		t.Errorf("all dots = %U, want U+28FF", r)
+	}
+
+	if r := braille(0, 0, none); r != '⠀' {
+		t.Errorf("no dots = %U, want U+2800", r)
+	}
+
+	// the last row is dots 7 and 8
+	bottom := func(x, y int) bool { return y == 3 }
+	if r := braille(0, 0, bottom); r != '⣀' {
+		t.Errorf("bottom row = %U, want U+28C0", r)
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		renderer   string
+		cols, rows int
+		want       int
+	}{
+		{"halfblock", 80, 24, 1},
+		{"halfblock", 200, 64, 3},
+		{"braille", 80, 24, 2},
+		{"braille", 10, 5, 1},
+	}
+
+	for _, tt := range tests {
+		if got := fit(tt.renderer, 64, 32, tt.cols, tt.rows); got != tt.want {
+			t.Errorf("fit(%s, %dx%d) = %d, want %d", tt.renderer, tt.cols, tt.rows, got, tt.want)
+		}
+	}
+}
+
+func TestRenderLinesHaveTheSameWidth(t *testing.T) {
+	fb := display.NewFramebuffer(64, 32)
+
+	for _, renderer := range RENDERERS {
+		lines := render(fb, renderer, 1, 3, palette.PRESETS["classic"], false)
+
+		for i, line := range lines {
+			if n := len([]rune(line)); n != len([]rune(lines[0])) {
+				t.Errorf("%s line %d is %d characters, want %d", renderer, i, n, len([]rune(lines[0])))
+			}
+
+			if !strings.HasPrefix(line, "   ") {
+				t.Errorf("%s line %d is not padded", renderer, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package tty
+
+import (
+	"errors"
+	"os"
+)
+
+var errUnsupported = errors.New("the terminal frontend is not supported on this system")
+
+func makeRaw(f *os.File) (func(), error) {
+	return nil, errUnsupported
+}
+
+func IsTerminal(f *os.File) bool {
+	return false
+}
+
+func size(f *os.File) (int, int, int, int, error) {
+	return 0, 0, 0, 0, errUnsupported
+}
+
+func notifyResize(c chan<- os.Signal) {}
@@ -0,0 +1,421 @@
+package tty
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/otaviohenrique/zamorak/pkg/display"
+	"github.com/otaviohenrique/zamorak/pkg/emulator"
+	"github.com/otaviohenrique/zamorak/pkg/headless"
+	"github.com/otaviohenrique/zamorak/pkg/keymap"
+	"github.com/otaviohenrique/zamorak/pkg/palette"
+)
+
+var (
+	// terminals only report keys going down, and repeat them while held, so
+	// a key is released when it was not seen for this long
+	DEFAULT_KEY_TIMEOUT = 200 * time.Millisecond
+
+	// names of the keys sent as a single character, as keymaps name them
+	characterKeys = map[byte]string{
+		' ': "Space", '\r': "Enter", '\t': "Tab", 0x7F: "Backspace",
+		',': "Comma", '.': "Period", ';': "Semicolon", '\'': "Quote",
+		'/': "Slash", '\\': "Backslash", '-': "Minus", '=': "Equal",
+		'[': "BracketLeft", ']': "BracketRight", '`': "Backquote",
+	}
+
+	// names of the keys sent as ESC [ or ESC O and a letter
+	escapeKeys = map[byte]string{
+		'A': "ArrowUp", 'B': "ArrowDown", 'C': "ArrowRight", 'D': "ArrowLeft",
+	}
+)
+
+const ctrlC = 0x03
+
+// Terminal runs an emulator in a text terminal, drawing the screen with
+// characters, or with pixels on terminals supporting a graphics protocol,
+// and reading the keypad from the keyboard. The emulator must run on the
+// headless runtime given to New.
+type Terminal struct {
+	emulator   *emulator.Emulator
+	runtime    *headless.Runtime
+	keymap     keymap.Keymap
+	palette    palette.Palette
+	renderer   string
+	color      bool
+	graphics   string // one of GRAPHICS, empty to draw with characters
+	keyTimeout time.Duration
+	in         *os.File
+	out        *os.File
+
+	lastSeen [16]time.Time // when every key was last reported
+	shown    []string      // lines on screen
+	frame    uint64        // hash of the framebuffer drawn with graphics
+	framed   bool          // whether frame is on screen
+	notice   string        // shown in the status line
+	beeping  bool
+}
+
+func New(e *emulator.Emulator, r *headless.Runtime) *Terminal {
+	t := new(Terminal)
+
+	t.emulator = e
+	t.runtime = r
+	t.keymap = keymap.PRESETS[keymap.DEFAULT_PRESET]
+	t.palette = palette.PRESETS[palette.DEFAULT_PRESET]
+	t.renderer = RENDERERS[0]
+	t.keyTimeout = DEFAULT_KEY_TIMEOUT
+	t.in = os.Stdin
+	t.out = os.Stdout
+
+	return t
+}
+
+func (t *Terminal) SetKeymap(k keymap.Keymap) {
+	t.keymap = k
+}
+
+// SetPalette draws the screen in the colors of a palette, with 24 bit color
+// escapes, when color is true.
+func (t *Terminal) SetPalette(p palette.Palette, color bool) {
+	t.palette = p
+	t.color = color
+}
+
+// SetRenderer picks one of RENDERERS.
+func (t *Terminal) SetRenderer(name string) error {
+	for _, r := range RENDERERS {
+		if r == name {
+			t.renderer = name
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown renderer %q, want one of %s", name, strings.Join(RENDERERS, ", "))
+}
+
+// SetGraphics draws the screen with one of GRAPHICS when the terminal
+// supports it, and with the renderer otherwise.
+func (t *Terminal) SetGraphics(protocol string) error {
+	for _, g := range GRAPHICS {
+		if g == protocol {
+			t.graphics = protocol
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown graphics protocol %q, want one of %s", protocol, strings.Join(GRAPHICS, ", "))
+}
+
+// SetKeyTimeout sets how long after its last repeat a key is released.
+func (t *Terminal) SetKeyTimeout(d time.Duration) {
+	t.keyTimeout = d
+}
+
+// Run takes over the terminal and runs the emulator at 60 frames per second
+// until Ctrl-C is pressed.
+func (t *Terminal) Run() error {
+	restore, err := makeRaw(t.in)
+	if err != nil {
+		return fmt.Errorf("could not set up the terminal: %w", err)
+	}
+	defer restore()
+
+	// alternate screen, hidden cursor
+	fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(t.out, "\x1b[0m\x1b[?25h\x1b[?1049l")
+
+	input := make(chan []byte, 16)
+	go t.read(input)
+
+	if t.graphics != "" {
+		if t.keys(t.detect(input)) {
+			return nil
+		}
+	}
+
+	if t.graphics == "kitty" {
+		// delete the screen image before leaving the alternate screen
+		defer fmt.Fprintf(t.out, "\x1b_Ga=d,d=I,i=%d,q=2\x1b\\", kittyImage)
+	}
+
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+
+	ticker := time.NewTicker(time.Second / 60)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case b, ok := <-input:
+			if !ok || t.keys(b) {
+				return nil
+			}
+		case <-resized:
+			// draw everything again at the new size
+			t.shown = nil
+			t.framed = false
+			fmt.Fprint(t.out, "\x1b[0m\x1b[2J")
+		case now := <-ticker.C:
+			t.release(now)
+			t.emulator.Update()
+			t.beep()
+
+			if err := t.draw(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// detect asks the terminal whether it supports the graphics protocol and
+// falls back to characters when it does not answer yes in time. It returns
+// the keys typed while waiting for the answer.
+func (t *Terminal) detect(input <-chan []byte) []byte {
+	fmt.Fprint(t.out, query(t.graphics))
+
+	timeout := time.After(DETECT_TIMEOUT)
+
+	var reply []byte
+	for {
+		select {
+		case b, ok := <-input:
+			if !ok {
+				t.fallback()
+
+				return nil
+			}
+
+			reply = append(reply, b...)
+
+			if supported, done, rest := answered(t.graphics, reply); done {
+				if !supported {
+					t.fallback()
+				}
+
+				return rest
+			}
+		case <-timeout:
+			t.fallback()
+
+			return nil
+		}
+	}
+}
+
+// fallback draws with characters when graphics are not supported.
+func (t *Terminal) fallback() {
+	t.notice = fmt.Sprintf("no %s graphics, drawing with %s", t.graphics, t.renderer)
+	t.graphics = ""
+}
+
+// read sends what is typed on the terminal until it is closed.
+func (t *Terminal) read(input chan<- []byte) {
+	defer close(input)
+
+	buf := make([]byte, 64)
+	for {
+		n, err := t.in.Read(buf)
+		if err != nil {
+			return
+		}
+
+		input <- append([]byte(nil), buf[:n]...)
+	}
+}
+
+// keys presses the CHIP-8 keys bound to what was typed, and reports whether
+// Ctrl-C was.
+func (t *Terminal) keys(b []byte) bool {
+	now := time.Now()
+
+	for i := 0; i < len(b); i++ {
+		var name string
+
+		switch c := b[i]; {
+		case c == ctrlC:
+			return true
+		case c == 0x1B:
+			var n int
+			name, n = escape(b[i:])
+			i += n - 1
+		case c >= 'a' && c <= 'z':
+			name = string(c - 'a' + 'A')
+		case c >= 'A' && c <= 'Z':
+			name = string(c)
+		case c >= '0' && c <= '9':
+			name = "Digit" + string(c)
+		default:
+			name = characterKeys[c]
+		}
+
+		if key, ok := t.keymap.Lookup(name); ok {
+			t.runtime.Press(key)
+			t.lastSeen[key] = now
+		}
+	}
+
+	return false
+}
+
+// escape reads the escape sequence b starts with, and returns the key it
+// names and its length. CSI (ESC [) and SS3 (ESC O) sequences run up to a
+// final byte between 0x40 and 0x7E, and only the arrows, without parameters,
+// name a key; a lone ESC is the Escape key.
+func escape(b []byte) (string, int) {
+	if len(b) < 2 || (b[1] != '[' && b[1] != 'O') {
+		return "Escape", 1
+	}
+
+	// parameter and intermediate bytes
+	end := 2
+	for end < len(b) && (b[end] < 0x40 || b[end] > 0x7E) {
+		end++
+	}
+
+	// cut short, drop what arrived
+	if end == len(b) {
+		return "", len(b)
+	}
+
+	if end > 2 {
+		return "", end + 1
+	}
+
+	return escapeKeys[b[end]], end + 1
+}
+
+// release lets go of the keys that were not repeated for the key timeout.
+func (t *Terminal) release(now time.Time) {
+	for key, seen := range t.lastSeen {
+		if !seen.IsZero() && now.Sub(seen) > t.keyTimeout {
+			t.runtime.Release(byte(key))
+			t.lastSeen[key] = time.Time{}
+		}
+	}
+}
+
+// beep rings the terminal bell when the sound timer starts.
+func (t *Terminal) beep() {
+	beeping := t.runtime.IsBeeping()
+
+	if beeping && !t.beeping {
+		fmt.Fprint(t.out, "\a")
+	}
+
+	t.beeping = beeping
+}
+
+// draw writes the lines of the screen that changed, scaled to fit the
+// terminal, and a status line below.
+func (t *Terminal) draw() error {
+	cols, rows, width, height, err := size(t.out)
+	if err != nil {
+		return err
+	}
+
+	fb := t.runtime.Framebuffer()
+
+	if t.graphics != "" {
+		return t.drawGraphics(fb, cols, rows, width, height)
+	}
+
+	// the last row holds the status
+	scale := fit(t.renderer, fb.Width(), fb.Height(), cols, rows-1)
+	cw, _ := cell(t.renderer)
+	pad := max((cols-fb.Width()*scale/cw)/2, 0)
+
+	lines := render(fb, t.renderer, scale, pad, t.palette, t.color)
+	lines = append(lines, t.status())
+
+	var b strings.Builder
+	for i, line := range lines {
+		if i < len(t.shown) && t.shown[i] == line {
+			continue
+		}
+
+		fmt.Fprintf(&b, "\x1b[%d;1H\x1b[2K%s", i+1, line)
+	}
+
+	t.shown = lines
+
+	return t.flush(&b)
+}
+
+// drawGraphics draws the screen as an image when it changed, as large as
+// whole pixels let it fit above the status line, and the status line on the
+// last row.
+func (t *Terminal) drawGraphics(fb *display.Framebuffer, cols, rows, width, height int) error {
+	cw, ch := DEFAULT_CELL_WIDTH, DEFAULT_CELL_HEIGHT
+	if width > 0 && height > 0 {
+		cw, ch = width/cols, height/rows
+	}
+
+	var b strings.Builder
+
+	if hash := fb.Hash(); !t.framed || hash != t.frame {
+		scale := fitPixels(fb.Width(), fb.Height(), cols*cw, (rows-1)*ch)
+		pad := max((cols-(fb.Width()*scale+cw-1)/cw)/2, 0)
+
+		img := frame(fb, t.palette, scale)
+
+		fmt.Fprintf(&b, "\x1b[1;%dH", pad+1)
+
+		if t.graphics == "kitty" {
+			seq, err := kitty(img)
+			if err != nil {
+				return err
+			}
+
+			b.WriteString(seq)
+		} else {
+			b.WriteString(sixel(img))
+		}
+
+		t.frame = hash
+		t.framed = true
+	}
+
+	// only the status line is kept in shown
+	if status := t.status(); len(t.shown) != 1 || t.shown[0] != status {
+		fmt.Fprintf(&b, "\x1b[%d;1H\x1b[2K%s", rows, status)
+		t.shown = []string{status}
+	}
+
+	return t.flush(&b)
+}
+
+// flush writes what was drawn, if anything.
+func (t *Terminal) flush(b *strings.Builder) error {
+	if b.Len() == 0 {
+		return nil
+	}
+
+	_, err := t.out.WriteString(b.String())
+
+	return err
+}
+
+// status describes the emulator under the screen.
+func (t *Terminal) status() string {
+	e := t.emulator
+
+	state := "running"
+	switch {
+	case e.Stopped() != nil:
+		state = "stopped: " + e.Stopped().Error()
+	case e.Paused():
+		state = "paused"
+	}
+
+	if t.notice != "" {
+		state += " - " + t.notice
+	}
+
+	return fmt.Sprintf("zamorak - %s - %s - Ctrl-C quits", filepath.Base(e.Path()), state)
+}
@@ -0,0 +1,31 @@
+package tty
+
+import "testing"
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		key  string
+		n    int
+	}{
+		{"lone escape", "\x1b", "Escape", 1},
+		{"escape then a key", "\x1bq", "Escape", 1},
+		{"arrow", "\x1b[A", "ArrowUp", 3},
+		{"application arrow", "\x1bOD", "ArrowLeft", 3},
+		{"arrow then a key", "\x1b[Cq", "ArrowRight", 3},
+		{"function key", "\x1b[15~q", "", 5},
+		{"modified arrow", "\x1b[1;5A", "", 6},
+		{"F1", "\x1bOP", "", 3},
+		{"cut short", "\x1b[1;", "", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, n := escape([]byte(tt.in))
+			if key != tt.key || n != tt.n {
+				t.Errorf("escape(%q) = %q, %d, want %q, %d", tt.in, key, n, tt.key, tt.n)
+			}
+		})
+	}
+}